@@ -0,0 +1,194 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Prefix of environment variables that define actions.
+const (
+	actionPrefix    = "ACTION_"
+	actionModSuffix = "_MOD"
+)
+
+var (
+	// Words that are upper-cased in action names
+	acronyms = map[string]bool{
+		"api": true, "aws": true, "dns": true, "ftp": true, "http": true,
+		"https": true, "ip": true, "rdp": true, "sftp": true, "ssh": true,
+		"ui": true, "url": true, "vnc": true, "vpn": true,
+	}
+	placeholderRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+	urlTemplateRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+)
+
+// Action is a user-defined action template, e.g.
+// "rsync -avz . {login}:" or "https://{hostname}:8443".
+//
+// Templates that start with a URL scheme are URLs to be opened. All other
+// templates are shell commands.
+//
+// Placeholders are replaced with the corresponding values of the Host:
+//
+//	{name}      Display name
+//	{host}      Hostname (also {hostname})
+//	{port}      Port
+//	{user}      Username (may be empty)
+//	{login}     user@host or host if there's no username
+//	{source}    Name of the source the host is from
+//	{url}       ssh:// URL
+//	{sftp_url}  sftp:// URL
+//	{ip}        First IP address
+//
+// The variables from Meta.Vars(), e.g. {tags} or {meta_<key>}, are also
+// available. Unknown placeholders are left unchanged.
+type Action struct {
+	Name     string // Display name
+	Template string // URL or shell command with placeholders
+	Modifier string // Modifier keys action is bound to, e.g. "cmd+alt"
+}
+
+// IsURL returns true if the Action opens a URL rather than running a
+// shell command.
+func (a Action) IsURL() bool { return urlTemplateRegex.MatchString(a.Template) }
+
+// Expand returns the URL or command line for Host. Values are shell-quoted
+// in commands. In URLs, they are escaped for the part of the URL they are
+// in, and IPv6 addresses in the host part are put in square brackets.
+// {login} may be used as the whole userinfo and host, e.g. "vnc://{login}".
+func (a Action) Expand(h Host) string {
+	vars := placeholderValues(h)
+	if !a.IsURL() {
+		return placeholderRegex.ReplaceAllStringFunc(a.Template, func(s string) string {
+			if v, ok := vars[s[1:len(s)-1]]; ok {
+				return ShellQuote(v)
+			}
+			return s
+		})
+	}
+
+	var (
+		tpl        = a.Template
+		authStart  = strings.Index(tpl, "://") + 3
+		authEnd    = len(tpl)
+		queryStart = len(tpl)
+		buf        strings.Builder
+		last       int
+	)
+	if i := strings.IndexAny(tpl[authStart:], "/?#"); i > -1 {
+		authEnd = authStart + i
+	}
+	if i := strings.IndexAny(tpl[authStart:], "?#"); i > -1 {
+		queryStart = authStart + i
+	}
+
+	for _, m := range placeholderRegex.FindAllStringSubmatchIndex(tpl, -1) {
+		key := tpl[m[2]:m[3]]
+		v, ok := vars[key]
+		if !ok {
+			continue
+		}
+		buf.WriteString(tpl[last:m[0]])
+		last = m[1]
+		switch {
+		case m[0] >= queryStart:
+			buf.WriteString(url.QueryEscape(v))
+		case m[0] >= authEnd:
+			buf.WriteString(url.PathEscape(v))
+		case key == "login" && h.Username() != "":
+			// Only the user part is escaped, so it stays user@host
+			buf.WriteString(url.User(h.Username()).String() + "@" + urlHost(h.Hostname(), 0))
+		case net.ParseIP(stripBrackets(v)) != nil:
+			buf.WriteString(urlHost(v, 0))
+		default: // hostname or userinfo
+			buf.WriteString(url.User(v).String())
+		}
+	}
+	buf.WriteString(tpl[last:])
+	return buf.String()
+}
+
+// placeholderValues returns the values for template placeholders.
+func placeholderValues(h Host) map[string]string {
+	vars := h.Meta().Vars()
+	vars["name"] = h.Name()
+	vars["host"] = h.Hostname()
+	vars["hostname"] = h.Hostname()
+	vars["port"] = strconv.Itoa(h.Port())
+	vars["user"] = h.Username()
+	vars["login"] = userHost(h.Username(), h.Hostname())
+	vars["source"] = h.Source()
+	vars["url"] = h.SSHURL().String()
+	vars["sftp_url"] = h.SFTPURL().String()
+	vars["ip"] = ""
+	if ips := h.Meta().IPs; len(ips) > 0 {
+		vars["ip"] = ips[0]
+	}
+	return vars
+}
+
+// ParseActions reads Actions from environment variables (in "KEY=value"
+// format, as returned by os.Environ()).
+//
+// ACTION_<NAME> defines the template for an action and ACTION_<NAME>_MOD
+// the modifier key(s) it is bound to. The display name of the action is
+// derived from <NAME>, so ACTION_OPEN_WEB_UI is called "Open Web UI".
+func ParseActions(environ []string) []Action {
+	var (
+		templates = map[string]string{}
+		mods      = map[string]string{}
+		actions   []Action
+	)
+
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv, actionPrefix) {
+			continue
+		}
+		key, value := kv[len(actionPrefix):i], strings.TrimSpace(kv[i+1:])
+		if value == "" {
+			continue
+		}
+		if strings.HasSuffix(key, actionModSuffix) {
+			mods[strings.TrimSuffix(key, actionModSuffix)] = value
+		} else {
+			templates[key] = value
+		}
+	}
+
+	for key, tpl := range templates {
+		actions = append(actions, Action{
+			Name:     actionName(key),
+			Template: tpl,
+			Modifier: mods[key],
+		})
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].Name < actions[j].Name })
+	return actions
+}
+
+// actionName generates a display name from an environment variable
+// suffix, e.g. "OPEN_WEB_UI" becomes "Open Web UI".
+func actionName(key string) string {
+	words := strings.Fields(strings.Replace(strings.ToLower(key), "_", " ", -1))
+	for i, w := range words {
+		if acronyms[w] {
+			words[i] = strings.ToUpper(w)
+		} else {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -19,6 +19,7 @@ JSON).
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/url"
@@ -26,11 +27,11 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
-
-	"strconv"
+	"unicode"
 
 	"os/exec"
 
+	"github.com/bmatcuk/doublestar"
 	ssh "github.com/deanishe/alfred-ssh"
 	aw "github.com/deanishe/awgo"
 	"github.com/deanishe/awgo/fuzzy"
@@ -57,6 +58,14 @@ var (
 	SSHGlobalConfigPath = "/etc/ssh/ssh_config"
 	SSHKnownHostsPath   = os.ExpandEnv("$HOME/.ssh/known_hosts")
 	EtcHostsPath        = "/etc/hosts"
+	PuTTYSessionsPath   = os.ExpandEnv("$HOME/.putty/sessions")
+	FileZillaSitesPath  = os.ExpandEnv("$HOME/.config/filezilla/sitemanager.xml")
+	// Default shell history files
+	ShellHistoryPaths = []string{
+		"~/.bash_history",
+		"~/.zsh_history",
+		"~/.local/share/fish/fish_history",
+	}
 	// HistoryVersion      = 2
 )
 
@@ -67,6 +76,20 @@ var (
 	PriorityHistory      = 3
 	PriorityGlobalConfig = 4
 	PriorityEtcHosts     = 5
+	PriorityInventory    = 6
+	PriorityCommand      = 7
+	PriorityAnsible      = 8
+	PriorityTerraform    = 9
+	PriorityVagrant      = 10
+	PriorityShellHistory = 11
+	PriorityPuTTY        = 12
+	PriorityFileZilla    = 13
+	PriorityGit          = 14
+	PriorityDocker       = 15
+	PriorityEC2          = 16
+	PriorityConsul       = 17
+	PriorityNetBox       = 18
+	PriorityJSON         = 19
 )
 
 // Workflow icons
@@ -96,6 +119,7 @@ is specified, the hostnames will be filtered against it.
 Usage:
     assh open <url>
     assh search [-d] [<query>]
+    assh actions [-d] <uid> [<query>]
     assh remember <url>
     assh forget <url>
     assh print (datadir|cachedir|distname|logfile)
@@ -118,8 +142,18 @@ Options:
 
 func init() {
 	aw.IconWarning = IconWarning
+}
+
+// main calls run() via Workflow.Run().
+func main() {
+	wf = newWorkflow()
+	wf.Run(run)
+}
 
-	wf = aw.New(
+// newWorkflow creates the Workflow. It isn't called in init(), as that
+// panics outside Alfred's environment, e.g. in tests.
+func newWorkflow() *aw.Workflow {
+	return aw.New(
 		aw.SortOptions(
 			fuzzy.SeparatorBonus(10.0),
 		),
@@ -132,9 +166,6 @@ func init() {
 	)
 }
 
-// main calls run() via Workflow.Run().
-func main() { wf.Run(run) }
-
 // Hosts is a collection of Host objects that supports aw.Sortable.
 // (and therefore sort.Interface).
 type Hosts []ssh.Host
@@ -153,6 +184,7 @@ func (s Hosts) SortKey(i int) string { return s[i].Name() }
 
 type options struct {
 	// Command-line options
+	Actions       bool   // Whether to show actions for a host
 	Check         bool   // Download list of available releases
 	Config        bool   // Whether to show configuration options
 	Demo          bool   `env:"DEMO_MODE"` // Whether to load test data instead of user data
@@ -168,26 +200,67 @@ type options struct {
 	Toggle        bool   // Whether to toggle a setting on/off
 	RawInput      string `docopt:"<query>"` // The full, unparsed query
 	RawURL        string `docopt:"<url>"`   // Input URL
+	UID           string `docopt:"<uid>"`   // UID of host to show actions for
 	VarName       string `docopt:"<var>"`   // Name of variable to toggle
 
 	// Workflow configuration (environment variables)
-	DisableConfig     bool
-	DisableEtcConfig  bool
-	DisableEtcHosts   bool
-	DisableHistory    bool
-	DisableKnownHosts bool
-	ExitOnSuccess     bool // Append " && exit" to shell commands
-	MoshCmd           string
-	SFTPApp           string `env:"SFTP_APP"`
-	SSHApp            string `env:"SSH_APP"`
-	SSHCmd            string `env:"SSH_CMD"`
+	AnsibleInventories   string        // Colon-separated paths of Ansible inventories
+	CommandSource        string        // Command that prints hosts as JSON
+	CommandSourceName    string        // Display name of command source
+	CommandSourceTimeout time.Duration // Max time command may run
+	CommandSourceCache   time.Duration // How long to cache command output
+	ConsulAddr           string        `env:"CONSUL_HTTP_ADDR"`   // URL of Consul agent
+	ConsulCache          time.Duration `env:"CONSUL_CACHE"`       // How long to cache Consul nodes
+	ConsulDatacenters    string        `env:"CONSUL_DATACENTERS"` // Consul datacentres to list nodes in
+	ConsulToken          string        `env:"CONSUL_HTTP_TOKEN"`  // Consul ACL token
+	DedupStrategy        string        `env:"DEDUP_STRATEGY"`     // Which hosts are duplicates
+	DisableConfig        bool
+	DisableDocker        bool
+	DisableEtcConfig     bool
+	DisableEtcHosts      bool
+	DisableFileZilla     bool `env:"DISABLE_FILEZILLA"`
+	DisableHistory       bool
+	DisableKnownHosts    bool
+	DisablePuTTY         bool `env:"DISABLE_PUTTY"`
+	DisableShellHistory  bool
+	DisableVagrant       bool
+	EC2Cache             time.Duration `env:"EC2_CACHE"`    // How long to cache EC2 instances
+	EC2Endpoint          string        `env:"EC2_ENDPOINT"` // Override URL of EC2 API
+	EC2Profiles          string        `env:"EC2_PROFILES"` // AWS profiles to list EC2 instances for
+	EC2Regions           string        `env:"EC2_REGIONS"`  // AWS regions to list EC2 instances in
+	ExitOnSuccess        bool          // Append " && exit" to shell commands
+	GitRepoDirs          string        // Colon-separated directories containing git repositories
+	HistorySyncDir       string        `env:"HISTORY_SYNC_DIR"` // Directory to sync history via
+	HistoryMachine       string        `env:"HISTORY_MACHINE"`  // Name of this machine's history journal
+	InventoryFiles       string        // Colon-separated paths of YAML/TOML/JSON inventories
+	JSONSourceCache      time.Duration `env:"JSON_SOURCE_CACHE"`   // How long to cache JSON document
+	JSONSourceFields     string        `env:"JSON_SOURCE_FIELDS"`  // Mapping of JSON fields to host attributes
+	JSONSourceHeaders    string        `env:"JSON_SOURCE_HEADERS"` // Newline-separated HTTP headers
+	JSONSourceName       string        `env:"JSON_SOURCE_NAME"`    // Display name of JSON source
+	JSONSourceURL        string        `env:"JSON_SOURCE_URL"`     // URL or path of JSON document
+	MoshCmd              string
+	NetBoxCache          time.Duration `env:"NETBOX_CACHE"`    // How long to cache NetBox objects
+	NetBoxRoles          string        `env:"NETBOX_ROLES"`    // Only list NetBox objects with these roles
+	NetBoxSites          string        `env:"NETBOX_SITES"`    // Only list NetBox objects at these sites
+	NetBoxStatuses       string        `env:"NETBOX_STATUSES"` // Only list NetBox objects with these statuses
+	NetBoxToken          string        `env:"NETBOX_TOKEN"`    // NetBox API token
+	NetBoxURL            string        `env:"NETBOX_URL"`      // URL of NetBox
+	SFTPApp              string        `env:"SFTP_APP"`
+	ShellHistoryFiles    string        // Colon-separated paths of shell history files
+	SourceTimeout        time.Duration `env:"SOURCE_TIMEOUT"` // Max time to load each source
+	SSHApp               string        `env:"SSH_APP"`
+	SSHCmd               string        `env:"SSH_CMD"`
+	TerraformStates      string        // Colon-separated glob patterns of Terraform state files
+	VagrantCmd           string        // Path to vagrant program
 
 	// Derived configuration
-	query       string   // User query. User input is parsed into query and username
-	url         *url.URL // URL to add to history
-	username    string   // SSH username. Added later by query parser.
-	port        int      // SSH port. Added later by query parser.
-	historyPath string   // Path to history cache file
+	query       string            // User query. User input is parsed into query and username
+	url         *url.URL          // URL to add to history
+	username    string            // SSH username. Added later by query parser.
+	port        int               // SSH port. Added later by query parser.
+	historyPath string            // Path to history cache file
+	actions     []ssh.Action      // User-defined actions
+	dedup       ssh.DedupStrategy // Parsed DedupStrategy
 }
 
 // MagicAction that opens a given path or URL.
@@ -250,6 +323,13 @@ func parseArgs() *options {
 		o.query = o.RawInput
 	}
 
+	o.actions = ssh.ParseActions(os.Environ())
+
+	if o.dedup, err = ssh.ParseDedupStrategy(o.DedupStrategy); err != nil {
+		log.Printf("[config] %v, using %q", err, ssh.DefaultDedupStrategy)
+		o.dedup = ssh.DefaultDedupStrategy
+	}
+
 	return o
 }
 
@@ -328,7 +408,7 @@ func runHistory(o *options) {
 		return
 	}
 
-	h := ssh.NewHistory(o.historyPath, "history", 1)
+	h := newHistory(o, 1)
 	if err := h.Load(); err != nil {
 		log.Printf("Error loading history : %v", err)
 		panic(err)
@@ -365,8 +445,13 @@ func runConfig(opts *options) {
 		{"SSH Config (system)", "/etc/ssh/ssh_config",
 			"DISABLE_ETC_CONFIG", opts.DisableEtcConfig},
 		{"/etc/hosts", "/etc/hosts", "DISABLE_ETC_HOSTS", opts.DisableEtcHosts},
+		{"Docker", "Docker Machines and ssh:// contexts", "DISABLE_DOCKER", opts.DisableDocker},
+		{"FileZilla", "SFTP sites in FileZilla's Site Manager", "DISABLE_FILEZILLA", opts.DisableFileZilla},
 		{"History", "workflow history", "DISABLE_HISTORY", opts.DisableHistory},
 		{"Known Hosts", "~/.ssh/known_hosts", "DISABLE_KNOWN_HOSTS", opts.DisableKnownHosts},
+		{"PuTTY", "~/.putty/sessions", "DISABLE_PUTTY", opts.DisablePuTTY},
+		{"Shell History", "ssh commands in shell history", "DISABLE_SHELL_HISTORY", opts.DisableShellHistory},
+		{"Vagrant", "running Vagrant machines", "DISABLE_VAGRANT", opts.DisableVagrant},
 	}
 
 	wf.Var("query", opts.query)
@@ -456,18 +541,8 @@ func runSearch(o *options) {
 	)
 
 	// Parse query ----------------------------------------------------
-	// Extract username if present
-	if i := strings.Index(o.query, "@"); i > -1 {
-		o.username, o.query = o.query[:i], o.query[i+1:]
-	}
-	// Extract port if present
-	if i := strings.Index(o.query, ":"); i > -1 {
-		var port string
-		o.query, port = o.query[:i], o.query[i+1:]
-		if v, err := strconv.Atoi(port); err == nil {
-			o.port = v
-		}
-	}
+	// Extract username and port if present
+	o.username, o.query, o.port = ssh.ParseQuery(o.query)
 
 	log.Printf("query=%v, username=%v, port=%v", o.query, o.username, o.port)
 
@@ -485,13 +560,13 @@ func runSearch(o *options) {
 	}
 
 	// Load hosts from sources ----------------------------------------
-	hosts = loadHosts(o)
+	hosts, timedOut := loadHosts(o)
 	totalHosts := len(hosts)
 	// log.Printf("%d total host(s)", totalHosts)
 
 	// Prepare results for Alfred -------------------------------------
 	// seen := map[string]bool{}
-	d := ssh.Deduplicator{}
+	d := ssh.NewDuplicateFilter(o.dedup)
 	for _, host := range hosts {
 
 		// Force use of username/port parsed from input
@@ -510,6 +585,7 @@ func runSearch(o *options) {
 	}
 
 	// Filter hosts and/or add host from query ------------------------
+	warning, hint := "No matching hosts", "Try different input"
 	if o.query != "" {
 		// Filter hosts
 		res := wf.Filter(o.query)
@@ -525,12 +601,162 @@ func runSearch(o *options) {
 				itemForHost(host, o)
 			}
 		} else {
-			wf.WarnEmpty(fmt.Sprintf("Invalid hostname: %s", o.query), "Enter a different value")
+			warning, hint = fmt.Sprintf("Invalid hostname: %s", o.query), "Enter a different value"
+		}
+	}
+
+	addWarnings(warning, hint, timedOut)
+	wf.SendFeedback()
+}
+
+// addWarnings adds a warning item if there are no results, and tells
+// the user results may be incomplete if any sources timed out.
+// Unlike wf.WarnEmpty, it doesn't send feedback, so the timeout notice
+// is also shown when there are no results.
+func addWarnings(title, subtitle string, timedOut []string) {
+	if wf.IsEmpty() {
+		wf.NewItem(title).
+			Subtitle(subtitle).
+			Valid(false).
+			Icon(IconWarning)
+	}
+	if len(timedOut) > 0 {
+		wf.NewItem("Timed out: " + strings.Join(timedOut, ", ")).
+			Subtitle("Results may be incomplete. Increase SOURCE_TIMEOUT in the workflow settings?").
+			Valid(false).
+			Icon(IconWarning)
+	}
+}
+
+// hostAction is something that can be done with a Host.
+type hostAction struct {
+	title    string
+	subtitle string
+	arg      string
+	action   string // "connect", "copy", "forget" or "reveal"
+	shell    string // Value of shell_cmd variable for "connect" actions
+	icon     *aw.Icon
+}
+
+// hostActions returns all available actions for host.
+func hostActions(host ssh.Host, o *options) []hostAction {
+	var (
+		actions []hostAction
+		arg     string
+		shell   string
+	)
+
+	arg, shell = sshArg(host, o)
+	actions = append(actions, hostAction{"Connect with SSH", arg, arg, "connect", shell, IconWorkflow})
+
+	arg = host.SFTPURL().String()
+	sftp := hostAction{"Connect with SFTP", arg, arg, "connect", "0", IconWorkflow}
+	if host.Meta().Action == ssh.ActionSFTP {
+		actions = append([]hostAction{sftp}, actions...)
+	} else {
+		actions = append(actions, sftp)
+	}
+
+	if o.MoshCmd != "" {
+		arg = shellCommand(host.MoshCmd(o.MoshCmd), o)
+		actions = append(actions, hostAction{"Connect with mosh", arg, arg, "connect", "1", IconWorkflow})
+	}
+
+	arg = shellCommand(ssh.NewCommand("ping").AddTarget(host.Hostname()), o)
+	actions = append(actions, hostAction{"Ping " + host.Hostname(), arg, arg, "connect", "1", IconWorkflow})
+
+	prog := o.SSHCmd
+	if prog == "" {
+		prog = "ssh"
+	}
+	arg = host.SSHCmd(prog).String()
+	actions = append(actions, hostAction{"Copy SSH Command", arg, arg, "copy", "", IconURL})
+
+	arg = host.SSHURL().String()
+	actions = append(actions, hostAction{"Copy SSH URL", arg, arg, "copy", "", IconURL})
+
+	for _, a := range o.actions {
+		arg, shell = expandAction(a, host, o)
+		actions = append(actions, hostAction{a.Name, arg, arg, "connect", shell, IconWorkflow})
+	}
+
+	if p := host.Meta().Get("file"); p != "" {
+		actions = append(actions, hostAction{"Show in " + host.Source(),
+			"Reveal " + util.PrettyPath(p) + " in Finder", p, "reveal", "", IconSettings})
+	}
+
+	if h := historyHost(host); h != nil {
+		arg = h.SSHURL().String()
+		actions = append(actions, hostAction{"Forget Connection",
+			"Delete connection from history", arg, "forget", "", IconOff})
+	}
+
+	return actions
+}
+
+// findHost returns the Host with the given UID. If no loaded host has that
+// UID, e.g. because the user entered a username or port, the host is
+// reconstructed from the URL in the UID.
+func findHost(uid string, hosts []ssh.Host) ssh.Host {
+	for _, h := range hosts {
+		if h.UID() == uid {
+			return h
 		}
 	}
 
-	wf.WarnEmpty("No matching hosts", "Try different input")
+	i := strings.Index(uid, "||")
+	if i < 0 {
+		return nil
+	}
+	u, err := url.Parse(uid[i+2:])
+	if err != nil || u.Host == "" {
+		return nil
+	}
+
+	ref := ssh.NewBaseHostFromURL(u)
+	for _, h := range hosts {
+		if h.SSHURL().Hostname() != u.Hostname() {
+			continue
+		}
+		if ref.Username() != "" {
+			h.SetUsername(ref.Username())
+		}
+		if ref.Port() != h.Port() {
+			h.SetPort(ref.Port())
+		}
+		return h
+	}
+
+	return ssh.NewBaseHost(ref.Name(), ref.Hostname(), "user input", ref.Username(), ref.Port())
+}
+
+// Alfred Script Filter to show all actions for a host
+func runActions(o *options) {
+	hosts, _ := loadHosts(o)
+	host := findHost(o.UID, hosts)
+	if host == nil {
+		wf.Fatalf("Unknown host: %s", o.UID)
+	}
+	log.Printf("[actions] host=%s, source=%s", host.Name(), host.Source())
+
+	for _, a := range hostActions(host, o) {
+		wf.NewItem(a.title).
+			Subtitle(a.subtitle).
+			Arg(a.arg).
+			Copytext(a.arg).
+			Valid(true).
+			Icon(a.icon).
+			Var("action", a.action).
+			Var("shell_cmd", a.shell).
+			Var("source", host.Source()).
+			Var("url", host.SSHURL().String())
+	}
+
+	if o.query != "" {
+		wf.Filter(o.query)
+	}
 
+	wf.WarnEmpty("No matching actions", "Try a different query?")
 	wf.SendFeedback()
 }
 
@@ -569,6 +795,9 @@ func run() {
 	} else if o.Config {
 		runConfig(o)
 		return
+	} else if o.Actions {
+		runActions(o)
+		return
 	}
 	runSearch(o)
 
@@ -581,20 +810,34 @@ func itemForHost(host ssh.Host, o *options) *aw.Item {
 		title    = host.Name()
 		comp     = host.Name() // Autocomplete
 		key      = host.Name() // Sort key
+		meta     = host.Meta()
 		url      = host.SSHURL().String()
 		uid      = host.UID()
-		subtitle = fmt.Sprintf("%s (from %s)", url, host.Source())
+		from     = strings.Join(hostSources(host), ", ")
+		subtitle = fmt.Sprintf("%s (from %s)", url, from)
 	)
 
+	// Make other names and tags searchable
+	if m, ok := host.(*ssh.MergedHost); ok {
+		for _, name := range m.Names()[1:] {
+			key = key + " " + name
+		}
+	}
+	if len(meta.Tags) > 0 {
+		key = key + " " + strings.Join(meta.Tags, " ")
+	}
+
+	var prefix string
 	if o.username != "" && host.Username() == "" {
 		host.SetUsername(o.username)
-		comp = fmt.Sprintf("%s@%s", o.username, host.Name())
+		prefix = o.username + "@"
+		comp = prefix + host.Name()
 		title = comp
 	}
 
 	if o.port != 0 && o.port != host.Port() {
 		host.SetPort(o.port)
-		comp = fmt.Sprintf("%s:%d", comp, o.port)
+		comp = prefix + ssh.HostPort(host.Name(), o.port)
 		title = comp
 	}
 
@@ -619,46 +862,49 @@ func itemForHost(host ssh.Host, o *options) *aw.Item {
 		Var("shell_cmd", "0").
 		Var("url", url)
 
-	// Send ssh command via Terminal Command instead of opening URL
-	if os.Getenv("SSH_CMD") != "" {
-		cmd = host.SSHCmd(os.Getenv("SSH_CMD"))
-		if cmd != "" {
-			if o.ExitOnSuccess {
-				cmd += " && exit"
-			}
-			it.Arg(cmd)
-			it.Subtitle(fmt.Sprintf("%s (from %s)", cmd, host.Source()))
-			it.Var("shell_cmd", "1")
+	// Metadata, e.g. tags, for downstream actions
+	for k, v := range meta.Vars() {
+		it.Var(k, v)
+	}
+
+	target, shell := sshArg(host, o)
+	sftpURL := host.SFTPURL().String()
+
+	if meta.Action == ssh.ActionSFTP {
+		// Host prefers SFTP, so swap SFTP and SSH
+		it.Arg(sftpURL).
+			Copytext(sftpURL).
+			Subtitle(fmt.Sprintf("%s (from %s)", sftpURL, from))
+		it.NewModifier("cmd").
+			Arg(target).
+			Subtitle(fmt.Sprintf("Connect with SSH (%s)", target)).
+			Var("shell_cmd", shell)
+	} else {
+		// Send ssh command via Terminal Command instead of opening URL
+		if shell == "1" {
+			it.Arg(target)
+			it.Subtitle(fmt.Sprintf("%s (from %s)", target, from))
+			it.Var("shell_cmd", shell)
 		}
+		// Open SFTP connection instead
+		it.NewModifier("cmd").
+			Arg(sftpURL).
+			Subtitle(fmt.Sprintf("Connect with SFTP (%s)", sftpURL))
 	}
 
 	// Modifiers
 
-	// Open SFTP connection instead
-	url = host.SFTPURL().String()
-	it.NewModifier("cmd").
-		Arg(url).
-		Subtitle(fmt.Sprintf("Connect with SFTP (%s)", url))
-
 	// Open mosh connection instead
 	if os.Getenv("MOSH_CMD") != "" {
-		cmd = host.MoshCmd(os.Getenv("MOSH_CMD"))
-		if cmd != "" {
-			if o.ExitOnSuccess {
-				cmd += " && exit"
-			}
-			it.NewModifier("alt").
-				Subtitle(fmt.Sprintf("Connect with mosh (%s)", cmd)).
-				Arg(cmd).
-				Var("shell_cmd", "1")
-		}
+		cmd = shellCommand(host.MoshCmd(os.Getenv("MOSH_CMD")), o)
+		it.NewModifier("alt").
+			Subtitle(fmt.Sprintf("Connect with mosh (%s)", cmd)).
+			Arg(cmd).
+			Var("shell_cmd", "1")
 	}
 
 	// Ping host
-	cmd = "ping " + host.Hostname()
-	if o.ExitOnSuccess {
-		cmd += " && exit"
-	}
+	cmd = shellCommand(ssh.NewCommand("ping").AddTarget(host.Hostname()), o)
 	it.NewModifier("shift").
 		Subtitle(fmt.Sprintf("Ping %s", host.Hostname())).
 		Arg(cmd).
@@ -666,51 +912,354 @@ func itemForHost(host ssh.Host, o *options) *aw.Item {
 
 	// Delete connection from history
 	m := it.NewModifier("ctrl")
-	if host.Source() == "history" {
-		m.Subtitle("Delete connection from history").Arg(url).Valid(true)
+	if h := historyHost(host); h != nil {
+		m.Subtitle("Delete connection from history").Arg(h.SSHURL().String()).Valid(true)
 	} else {
 		m.Subtitle("Connection not from history").Valid(false)
 	}
+
+	// Show all actions
+	it.NewModifier("fn").
+		Subtitle("Show all actions").
+		Arg(uid).
+		Var("uid", uid)
+
+	// User-defined actions. These override the above.
+	for _, a := range o.actions {
+		if a.Modifier == "" {
+			continue
+		}
+		if strings.Contains(a.Modifier, "fn") {
+			log.Printf("[actions] ignored %q: fn is reserved for the action menu", a.Name)
+			continue
+		}
+		var keys []aw.ModKey
+		for _, k := range strings.Split(a.Modifier, "+") {
+			keys = append(keys, aw.ModKey(k))
+		}
+		arg, shell := expandAction(a, host, o)
+		it.NewModifier(keys...).
+			Subtitle(fmt.Sprintf("%s (%s)", a.Name, arg)).
+			Arg(arg).
+			Var("shell_cmd", shell)
+	}
 	return it
 }
 
-// loadHosts loads Hosts from all active sources.
-func loadHosts(o *options) []ssh.Host {
+// hostSources returns the names of the sources host was found in.
+func hostSources(host ssh.Host) []string {
+	if m, ok := host.(*ssh.MergedHost); ok {
+		return m.Sources()
+	}
+	return []string{host.Source()}
+}
+
+// historyHost returns host or the Host merged into it that is from
+// history, or nil if host isn't in the history.
+func historyHost(host ssh.Host) ssh.Host {
+	if m, ok := host.(*ssh.MergedHost); ok {
+		for _, h := range m.Hosts() {
+			if h.Source() == "history" {
+				return h
+			}
+		}
+		return nil
+	}
+	if host.Source() == "history" {
+		return host
+	}
+	return nil
+}
+
+// sshArg returns the arg to open an SSH connection to host and the value of
+// the shell_cmd variable. arg is an ssh:// URL, or an ssh command if SSH_CMD
+// is set.
+func sshArg(host ssh.Host, o *options) (arg, shell string) {
+	if o.SSHCmd != "" {
+		return shellCommand(host.SSHCmd(o.SSHCmd), o), "1"
+	}
+	return host.SSHURL().String(), "0"
+}
+
+// expandAction returns the arg for a user-defined action and the value
+// of the shell_cmd variable, i.e. whether arg is a shell command or a URL.
+func expandAction(a ssh.Action, host ssh.Host, o *options) (arg, shell string) {
+	if a.IsURL() {
+		return a.Expand(host), "0"
+	}
+	s := a.Expand(host)
+	if o.ExitOnSuccess {
+		s += " && exit"
+	}
+	return s, "1"
+}
+
+// shellCommand returns the command line for cmd to be run in the user's
+// terminal, with " && exit" appended if so configured.
+func shellCommand(cmd *ssh.Command, o *options) string {
+	s := cmd.String()
+	if o.ExitOnSuccess {
+		s += " && exit"
+	}
+	return s
+}
+
+// loadHosts loads Hosts from all active sources. It also returns the names
+// of any sources that took too long to load.
+func loadHosts(o *options) ([]ssh.Host, []string) {
 	var start = time.Now()
 	var hosts Hosts
+	var timedOut []string
 
 	if o.Demo {
 		log.Println("**** Using test data ****")
 		hosts = append(hosts, ssh.TestHosts()...)
-		return hosts
+		return hosts, nil
 	}
 
 	sources := ssh.Sources{}
+	cache := ssh.NewHostCache(filepath.Join(wf.CacheDir(), "hosts"))
 
 	if !o.DisableHistory {
-		sources = append(sources, ssh.NewHistory(o.historyPath, "history", PriorityHistory))
+		sources = append(sources, newHistory(o, PriorityHistory))
 		// log.Printf("[source/new/history] %s", aw.ShortenPath(o.historyPath))
 	}
 	if !o.DisableEtcHosts {
-		sources = append(sources, ssh.NewHostsSource(EtcHostsPath, "/etc/hosts", PriorityEtcHosts))
+		s := ssh.NewHostsSource(EtcHostsPath, "/etc/hosts", PriorityEtcHosts)
+		s.Cache = cache
+		sources = append(sources, s)
 		// log.Printf("[source/new/hosts] %s", EtcHostsPath)
 	}
 	if !o.DisableKnownHosts {
-		sources = append(sources, ssh.NewKnownSource(SSHKnownHostsPath, "known_hosts", PriorityKnownHosts))
+		s := ssh.NewKnownSource(SSHKnownHostsPath, "known_hosts", PriorityKnownHosts)
+		s.Cache = cache
+		sources = append(sources, s)
 		// log.Printf("[source/new/known_hosts] %s", aw.ShortenPath(SSHKnownHostsPath))
 	}
 	if !o.DisableConfig {
-		sources = append(sources, ssh.NewConfigSource(SSHUserConfigPath, "~/.ssh/config", PriorityUserConfig))
+		s := ssh.NewConfigSource(SSHUserConfigPath, "~/.ssh/config", PriorityUserConfig)
+		s.Cache = cache
+		sources = append(sources, s)
 		// log.Printf("[source/new/config] %s", aw.ShortenPath(SSHUserConfigPath))
 	}
 	if !o.DisableEtcConfig {
-		sources = append(sources, ssh.NewConfigSource(SSHGlobalConfigPath, "/etc/ssh", PriorityGlobalConfig))
+		s := ssh.NewConfigSource(SSHGlobalConfigPath, "/etc/ssh", PriorityGlobalConfig)
+		s.Cache = cache
+		sources = append(sources, s)
 		// log.Printf("[source/new/config] %s", SSHGlobalConfigPath)
 	}
-	hosts = append(hosts, sources.Hosts()...)
+	for _, path := range filepath.SplitList(o.InventoryFiles) {
+		path = expandPath(path)
+		s := ssh.NewInventorySource(path, util.PrettyPath(path), PriorityInventory)
+		s.Cache = cache
+		sources = append(sources, s)
+	}
+	for _, path := range filepath.SplitList(o.AnsibleInventories) {
+		path = expandPath(path)
+		s := ssh.NewAnsibleSource(path, util.PrettyPath(path), PriorityAnsible)
+		s.Cache = cache
+		sources = append(sources, s)
+	}
+	for _, pattern := range filepath.SplitList(o.TerraformStates) {
+		paths, err := doublestar.Glob(expandPath(pattern))
+		if err != nil {
+			log.Printf("[source/new/terraform] invalid pattern %q: %v", pattern, err)
+			continue
+		}
+		for _, path := range paths {
+			s := ssh.NewTerraformSource(path, util.PrettyPath(path), PriorityTerraform)
+			s.Cache = cache
+			sources = append(sources, s)
+		}
+	}
+	for _, path := range filepath.SplitList(o.GitRepoDirs) {
+		path = expandPath(path)
+		s := ssh.NewGitSource(path, util.PrettyPath(path), PriorityGit)
+		s.Cache = cache
+		sources = append(sources, s)
+	}
+	if !o.DisableShellHistory {
+		paths := filepath.SplitList(o.ShellHistoryFiles)
+		if len(paths) == 0 {
+			paths = ShellHistoryPaths
+		}
+		for _, path := range paths {
+			path = expandPath(path)
+			if !util.PathExists(path) {
+				continue
+			}
+			s := ssh.NewShellHistorySource(path, util.PrettyPath(path), PriorityShellHistory)
+			s.Cache = cache
+			sources = append(sources, s)
+		}
+	}
+	if !o.DisablePuTTY && util.PathExists(PuTTYSessionsPath) {
+		s := ssh.NewPuTTYSource(PuTTYSessionsPath, "PuTTY", PriorityPuTTY)
+		s.Cache = cache
+		sources = append(sources, s)
+	}
+	if !o.DisableFileZilla && util.PathExists(FileZillaSitesPath) {
+		s := ssh.NewFileZillaSource(FileZillaSitesPath, "FileZilla", PriorityFileZilla)
+		s.Cache = cache
+		sources = append(sources, s)
+	}
+	if !o.DisableDocker {
+		if path := dockerConfigPath(); util.PathExists(path) {
+			s := ssh.NewDockerSource(path, "Docker", PriorityDocker)
+			s.Cache = cache
+			sources = append(sources, s)
+		}
+	}
+	if !o.DisableVagrant {
+		if path := vagrantIndexPath(); util.PathExists(path) {
+			s := ssh.NewVagrantSource(path, "Vagrant", PriorityVagrant)
+			s.Cache = cache
+			if o.VagrantCmd != "" {
+				s.Command = o.VagrantCmd
+			}
+			sources = append(sources, s)
+		}
+	}
+	if o.CommandSource != "" {
+		name := o.CommandSourceName
+		if name == "" {
+			name = "command"
+		}
+		s := ssh.NewCommandSource(o.CommandSource, name, PriorityCommand, o.CommandSourceTimeout)
+		s.Cache = cache
+		if o.CommandSourceCache != 0 {
+			s.MaxAge = o.CommandSourceCache
+		}
+		sources = append(sources, s)
+	}
+	for _, profile := range splitList(o.EC2Profiles) {
+		regions := splitList(o.EC2Regions)
+		if len(regions) == 0 {
+			regions = []string{""} // profile's default region
+		}
+		for _, region := range regions {
+			s := ssh.NewEC2Source(profile, region, "", PriorityEC2, 0)
+			s.Cache = cache
+			s.Endpoint = o.EC2Endpoint
+			if o.EC2Cache != 0 {
+				s.MaxAge = o.EC2Cache
+			}
+			sources = append(sources, s)
+		}
+	}
+	if o.ConsulAddr != "" {
+		s := ssh.NewConsulSource(o.ConsulAddr, "Consul", PriorityConsul, 0)
+		s.Cache = cache
+		s.Token = o.ConsulToken
+		s.Datacenters = splitList(o.ConsulDatacenters)
+		if o.ConsulCache != 0 {
+			s.MaxAge = o.ConsulCache
+		}
+		sources = append(sources, s)
+	}
+	if o.NetBoxURL != "" {
+		s := ssh.NewNetBoxSource(o.NetBoxURL, o.NetBoxToken, "NetBox", PriorityNetBox, 0)
+		s.Cache = cache
+		s.Sites = splitList(o.NetBoxSites)
+		s.Roles = splitList(o.NetBoxRoles)
+		s.Statuses = splitList(o.NetBoxStatuses)
+		if o.NetBoxCache != 0 {
+			s.MaxAge = o.NetBoxCache
+		}
+		sources = append(sources, s)
+	}
+	if o.JSONSourceURL != "" {
+		if s, err := jsonSource(o); err != nil {
+			log.Printf("[source/new/json] %v", err)
+		} else {
+			s.Cache = cache
+			sources = append(sources, s)
+		}
+	}
+	timeout := o.SourceTimeout
+	if timeout == 0 {
+		timeout = ssh.DefaultSourceTimeout
+	}
+	loaded, failed := sources.Load(context.Background(), timeout, ssh.NewDuplicateFilter(o.dedup))
+	hosts = append(hosts, loaded...)
+	for _, s := range failed {
+		timedOut = append(timedOut, s.Name())
+	}
 
 	log.Printf("%d host(s) loaded in %s", len(hosts), time.Since(start))
-	return hosts
+	return hosts, timedOut
+}
+
+// newHistory creates a History configured from the workflow settings.
+func newHistory(o *options, priority int) *ssh.History {
+	h := ssh.NewHistory(o.historyPath, "history", priority)
+	h.Dedup = o.dedup
+	if o.HistorySyncDir != "" && !o.Demo {
+		h.SyncDir = expandPath(o.HistorySyncDir)
+		h.Machine = o.HistoryMachine
+		log.Printf("[history] syncing via %s", util.PrettyPath(h.SyncDir))
+	}
+	return h
+}
+
+// vagrantIndexPath returns the path of Vagrant's global machine index.
+func vagrantIndexPath() string {
+	dir := os.Getenv("VAGRANT_HOME")
+	if dir == "" {
+		dir = "~/.vagrant.d"
+	}
+	return filepath.Join(expandPath(dir), "data/machine-index/index")
+}
+
+// jsonSource creates the JSONSource configured by JSON_SOURCE_* variables.
+func jsonSource(o *options) (*ssh.JSONSource, error) {
+	fields, err := ssh.ParseJSONFields(o.JSONSourceFields)
+	if err != nil {
+		return nil, err
+	}
+	u := o.JSONSourceURL
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		u = expandPath(u)
+	}
+	name := o.JSONSourceName
+	if name == "" {
+		name = "json"
+	}
+
+	s := ssh.NewJSONSource(u, fields, name, PriorityJSON, 0)
+	for _, line := range strings.Split(o.JSONSourceHeaders, "\n") {
+		i := strings.Index(line, ":")
+		if i < 1 {
+			continue
+		}
+		s.Headers.Add(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+	}
+	if o.JSONSourceCache != 0 {
+		s.MaxAge = o.JSONSourceCache
+	}
+	return s, nil
+}
+
+// dockerConfigPath returns the path of Docker's config directory.
+func dockerConfigPath() string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		dir = "~/.docker"
+	}
+	return expandPath(dir)
+}
+
+// splitList splits a comma- or space-separated list.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+}
+
+// expandPath expands a leading ~ and environment variables in path.
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = "$HOME" + path[1:]
+	}
+	return os.ExpandEnv(path)
 }
 
 /*
@@ -0,0 +1,71 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	aw "github.com/deanishe/awgo"
+)
+
+// TestAddWarnings tests that timed-out sources are listed, also when
+// there are no results.
+func TestAddWarnings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assh-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := aw.MapEnv{
+		aw.EnvVarBundleID: "net.deanishe.alfred-ssh",
+		aw.EnvVarCacheDir: dir,
+		aw.EnvVarDataDir:  dir,
+	}
+
+	var data = []struct {
+		hosts    []string
+		timedOut []string
+		x        []string
+	}{
+		{nil, nil, []string{"No matching hosts"}},
+		{nil, []string{"EC2"}, []string{"No matching hosts", "Timed out: EC2"}},
+		{[]string{"a.example.com"}, nil, []string{"a.example.com"}},
+		{[]string{"a.example.com"}, []string{"EC2", "Consul"},
+			[]string{"a.example.com", "Timed out: EC2, Consul"}},
+	}
+
+	for _, td := range data {
+		wf = aw.NewFromEnv(env)
+		for _, s := range td.hosts {
+			wf.NewItem(s)
+		}
+		addWarnings("No matching hosts", "Try different input", td.timedOut)
+
+		var v []string
+		for _, it := range wf.Feedback.Items {
+			js, err := it.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var item struct{ Title string }
+			if err := json.Unmarshal(js, &item); err != nil {
+				t.Fatal(err)
+			}
+			v = append(v, item.Title)
+		}
+		if !reflect.DeepEqual(v, td.x) {
+			t.Errorf("Bad items for %v. Expected=%v, Got=%v", td.timedOut, td.x, v)
+		}
+	}
+}
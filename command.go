@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"strings"
+)
+
+// Command is a shell command built from an argument vector. Arguments are
+// only quoted for the shell when the command is converted to a string,
+// so hostnames, usernames etc. can't inject anything into the shell.
+type Command struct {
+	// Program to run. Inserted into the command line verbatim, so
+	// it may contain options, e.g. "ssh -A". This is the user's own
+	// configuration, so it isn't quoted.
+	Program string
+	// Arguments to Program. Each is quoted for the shell if necessary.
+	Args []string
+}
+
+// NewCommand creates a new Command for program with args.
+func NewCommand(program string, args ...string) *Command {
+	return &Command{Program: program, Args: args}
+}
+
+// Add appends arguments to Command.
+func (c *Command) Add(args ...string) *Command {
+	c.Args = append(c.Args, args...)
+	return c
+}
+
+// AddTarget appends the destination argument (e.g. "user@host") to the
+// Command. If it looks like an option, it is preceded by "--", so the
+// program doesn't interpret it as one.
+func (c *Command) AddTarget(target string) *Command {
+	if strings.HasPrefix(target, "-") {
+		c.Args = append(c.Args, "--")
+	}
+	return c.Add(target)
+}
+
+// String returns the command line with arguments quoted for a POSIX shell.
+func (c *Command) String() string {
+	words := make([]string, 0, len(c.Args)+1)
+	if c.Program != "" {
+		words = append(words, c.Program)
+	}
+	for _, s := range c.Args {
+		words = append(words, ShellQuote(s))
+	}
+	return strings.Join(words, " ")
+}
+
+// ShellQuote quotes s for a POSIX shell. s is returned unchanged if it
+// contains no special characters.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if isShellSafe(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+// isShellSafe returns true if s contains no characters with special
+// meaning to the shell.
+func isShellSafe(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("@%+=:,./_-", c) > -1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// userHost returns "user@host" or "host" if user is empty.
+func userHost(user, host string) string {
+	if user == "" {
+		return host
+	}
+	return user + "@" + host
+}
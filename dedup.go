@@ -0,0 +1,178 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DedupStrategy determines which Hosts are considered duplicates.
+type DedupStrategy string
+
+// Available strategies.
+const (
+	// DedupUID only removes Hosts with the same UID, i.e. the same
+	// name, username, hostname and port.
+	DedupUID DedupStrategy = "uid"
+	// DedupHostname merges Hosts with the same hostname and port.
+	DedupHostname DedupStrategy = "hostname"
+	// DedupIP merges Hosts with the same hostname or IP address and
+	// port. Addresses are resolved from the sources, e.g. /etc/hosts
+	// or known_hosts, not via DNS.
+	DedupIP DedupStrategy = "ip"
+	// DedupNone keeps all Hosts.
+	DedupNone DedupStrategy = "none"
+
+	// DefaultDedupStrategy is used if no strategy is specified.
+	DefaultDedupStrategy = DedupUID
+)
+
+// ParseDedupStrategy returns the DedupStrategy with name s. An empty
+// string returns DefaultDedupStrategy.
+func ParseDedupStrategy(s string) (DedupStrategy, error) {
+	switch ds := DedupStrategy(strings.ToLower(strings.TrimSpace(s))); ds {
+	case "":
+		return DefaultDedupStrategy, nil
+	case DedupUID, DedupHostname, DedupIP, DedupNone:
+		return ds, nil
+	}
+	return "", fmt.Errorf("unknown dedup strategy: %q", s)
+}
+
+// DuplicateFilter recognises duplicate Hosts according to a
+// DedupStrategy.
+type DuplicateFilter interface {
+	// Add records a Host as seen.
+	Add(h Host)
+	// IsDuplicate returns true if Host is a duplicate of one passed
+	// to Add. It agrees with Filter on whether two Hosts are duplicates.
+	IsDuplicate(h Host) bool
+	// Filter removes or merges the duplicates in hosts, which must be
+	// sorted by priority. It ignores the Hosts passed to Add.
+	Filter(hosts []Host) []Host
+}
+
+// NewDuplicateFilter returns a new DuplicateFilter for the given
+// strategy. An unknown strategy returns a DuplicateFilter for
+// DefaultDedupStrategy.
+func NewDuplicateFilter(s DedupStrategy) DuplicateFilter {
+	switch s {
+	case DedupUID:
+		return &Deduplicator{}
+	case DedupHostname:
+		return &endpointDeduplicator{keyFunc: hostnameKeys}
+	case DedupNone:
+		return noopDeduplicator{}
+	case DedupIP:
+		return &endpointDeduplicator{keyFunc: addressKeys}
+	}
+	return NewDuplicateFilter(DefaultDedupStrategy)
+}
+
+// FilterDuplicateHosts removes Hosts with the same UID.
+func FilterDuplicateHosts(hosts []Host) []Host {
+	return (&Deduplicator{}).Filter(hosts)
+}
+
+// Deduplicator recognises duplicate Hosts. It implements DuplicateFilter
+// for the DedupUID strategy.
+type Deduplicator struct {
+	tags map[string]bool
+}
+
+// Add adds a new Host.
+func (d *Deduplicator) Add(h Host) {
+	if d.tags == nil {
+		d.tags = map[string]bool{}
+	}
+	d.tags[h.UID()] = true
+}
+
+// IsDuplicate returns true if Host is a duplicate.
+func (d *Deduplicator) IsDuplicate(h Host) bool { return d.tags[h.UID()] }
+
+// Filter implements DuplicateFilter.
+func (d *Deduplicator) Filter(hosts []Host) []Host {
+	var (
+		clean = []Host{}
+		seen  = &Deduplicator{}
+	)
+	for _, h := range hosts {
+		if seen.IsDuplicate(h) {
+			continue
+		}
+		clean = append(clean, h)
+		seen.Add(h)
+	}
+	return clean
+}
+
+// endpointDeduplicator implements the DedupHostname and DedupIP
+// strategies. Hosts that share any of the keys returned by keyFunc
+// are duplicates unless they have different usernames. As in
+// MergeHosts, a Host without a username is compatible with any other.
+type endpointDeduplicator struct {
+	keyFunc func(Host) []string
+	seen    map[string]map[string]bool // key -> usernames
+}
+
+// Add implements DuplicateFilter.
+func (d *endpointDeduplicator) Add(h Host) {
+	if d.seen == nil {
+		d.seen = map[string]map[string]bool{}
+	}
+	u := h.Username()
+	for _, k := range d.keyFunc(h) {
+		if d.seen[k] == nil {
+			d.seen[k] = map[string]bool{}
+		}
+		d.seen[k][u] = true
+	}
+}
+
+// IsDuplicate implements DuplicateFilter. If h has a username and is a
+// duplicate of a Host without one, that Host takes h's username (as
+// it would in MergeHosts), so Hosts with other usernames aren't
+// duplicates of it.
+func (d *endpointDeduplicator) IsDuplicate(h Host) bool {
+	u := h.Username()
+	for _, k := range d.keyFunc(h) {
+		users := d.seen[k]
+		switch {
+		case len(users) == 0:
+			continue
+		case u == "" || users[u]:
+			return true
+		case users[""]:
+			delete(users, "")
+			users[u] = true
+			return true
+		}
+	}
+	return false
+}
+
+// Filter implements DuplicateFilter. Duplicates are combined into a
+// MergedHost.
+func (d *endpointDeduplicator) Filter(hosts []Host) []Host {
+	return mergeHosts(FilterDuplicateHosts(hosts), d.keyFunc)
+}
+
+// noopDeduplicator implements the DedupNone strategy.
+type noopDeduplicator struct{}
+
+// Add implements DuplicateFilter.
+func (d noopDeduplicator) Add(h Host) {}
+
+// IsDuplicate implements DuplicateFilter.
+func (d noopDeduplicator) IsDuplicate(h Host) bool { return false }
+
+// Filter implements DuplicateFilter.
+func (d noopDeduplicator) Filter(hosts []Host) []Host { return hosts }
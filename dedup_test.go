@@ -0,0 +1,124 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestDedupStrategies tests the number of hosts left by each strategy.
+func TestDedupStrategies(t *testing.T) {
+	newHost := func(name, hostname, user string, ips ...string) Host {
+		h := NewBaseHost(name, hostname, "test", user, 22)
+		h.meta.AddIP(ips...)
+		return h
+	}
+	hosts := []Host{
+		newHost("web1", "web1.example.com", ""),
+		newHost("web1", "web1.example.com", ""),
+		newHost("web1.example.com", "web1.example.com", "", "10.0.0.7"),
+		newHost("10.0.0.7", "10.0.0.7", ""),
+		newHost("root@10.0.0.7", "10.0.0.7", "root"),
+	}
+
+	tests := []struct {
+		strategy    DedupStrategy
+		expected    int // Hosts left by Filter
+		incremental int // Hosts left by IsDuplicate/Add
+	}{
+		{DedupNone, 5, 5},
+		{DedupUID, 4, 4},
+		{DedupHostname, 2, 2},
+		{DedupIP, 1, 2},
+	}
+
+	for _, td := range tests {
+		d := NewDuplicateFilter(td.strategy)
+		if n := len(d.Filter(hosts)); n != td.expected {
+			t.Errorf("[%s] Expected=%d, Got=%d", td.strategy, td.expected, n)
+		}
+
+		// Incremental deduplication
+		n := 0
+		for _, h := range hosts {
+			if !d.IsDuplicate(h) {
+				d.Add(h)
+				n++
+			}
+		}
+		if n != td.incremental {
+			t.Errorf("[%s] Expected %d unique hosts, Got=%d", td.strategy, td.incremental, n)
+		}
+	}
+}
+
+// TestDedupUsernames tests that Filter and IsDuplicate agree on which
+// usernames are compatible.
+func TestDedupUsernames(t *testing.T) {
+	tests := []struct {
+		users []string // Of hosts with the same endpoint
+		x     int      // Hosts left
+	}{
+		{[]string{"", ""}, 1},
+		{[]string{"", "alice"}, 1},
+		{[]string{"alice", ""}, 1},
+		{[]string{"alice", "alice"}, 1},
+		{[]string{"alice", "bob"}, 2},
+		{[]string{"", "alice", "bob"}, 2},
+		{[]string{"alice", "", "bob"}, 2},
+	}
+
+	for _, strategy := range []DedupStrategy{DedupHostname, DedupIP} {
+		for _, td := range tests {
+			var hosts []Host
+			for i, u := range td.users {
+				hosts = append(hosts, NewBaseHost(fmt.Sprintf("host%d", i), "host.example.com", "test", u, 22))
+			}
+
+			if n := len(NewDuplicateFilter(strategy).Filter(hosts)); n != td.x {
+				t.Errorf("[%s] Filter(%q): Expected=%d, Got=%d", strategy, td.users, td.x, n)
+			}
+
+			d := NewDuplicateFilter(strategy)
+			n := 0
+			for _, h := range hosts {
+				if !d.IsDuplicate(h) {
+					d.Add(h)
+					n++
+				}
+			}
+			if n != td.x {
+				t.Errorf("[%s] IsDuplicate(%q): Expected=%d, Got=%d", strategy, td.users, td.x, n)
+			}
+		}
+	}
+}
+
+// TestParseDedupStrategy tests parsing of strategy names.
+func TestParseDedupStrategy(t *testing.T) {
+	for s, x := range map[string]DedupStrategy{
+		"":          DefaultDedupStrategy,
+		"uid":       DedupUID,
+		" Hostname": DedupHostname,
+		"IP":        DedupIP,
+		"none":      DedupNone,
+	} {
+		v, err := ParseDedupStrategy(s)
+		if err != nil {
+			t.Errorf("[%q] Unexpected error: %v", s, err)
+		}
+		if v != x {
+			t.Errorf("[%q] Expected=%q, Got=%q", s, x, v)
+		}
+	}
+	if _, err := ParseDedupStrategy("bogus"); err == nil {
+		t.Error("Accepted invalid strategy")
+	}
+}
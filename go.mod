@@ -1,7 +1,8 @@
 module github.com/deanishe/alfred-ssh
 
 require (
-	github.com/bmatcuk/doublestar v1.1.2
+	github.com/BurntSushi/toml v0.4.1
+	github.com/bmatcuk/doublestar v1.1.5
 	github.com/deanishe/awgo v0.20.2
 	github.com/disintegration/imaging v1.6.0
 	github.com/docopt/docopt-go v0.0.0-20180111231733-ee0de3bc6815
@@ -9,6 +10,8 @@ require (
 	github.com/magefile/mage v1.8.0
 	github.com/pkg/errors v0.8.1
 	golang.org/x/image v0.0.0-20190703141733-d6a02ce849c9 // indirect
-	golang.org/x/text v0.3.2
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v2 v2.4.0
 	howett.net/plist v0.0.0-20181124034731-591f970eefbb
 )
@@ -12,72 +12,32 @@ import (
 	"fmt"
 	"net"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-var (
-	hostnameRegex = regexp.MustCompile("^[a-zA-Z0-9.-]+$")
+// Maximum lengths of a hostname and its labels (RFC 1123).
+const (
+	maxHostnameLength = 253
+	maxLabelLength    = 63
 )
 
 // Host is a host you can connect to.
 type Host interface {
-	UID() string                // Unique ID of host
-	Name() string               // Display name of host
-	Hostname() string           // Qualified hostname
-	Port() int                  // Port (22 by default)
-	SetPort(i int)              // Set the Port
-	Source() string             // Display name of source
-	Username() string           // Username if not default
-	SetUsername(n string)       // Set Username
-	CanonicalURL() *url.URL     // Canonical SSH URL
-	SSHURL() *url.URL           // ssh:// URL for this host
-	SFTPURL() *url.URL          // sftp:// URL for this host
-	SSHCmd(path string) string  // Command-line ssh command for this host
-	MoshCmd(path string) string // Command-line mosh command for this host
-}
-
-// Deduplicator recognises duplicate Hosts.
-type Deduplicator struct {
-	tags map[string]bool
-}
-
-// Add adds a new Host.
-func (d *Deduplicator) Add(h Host) {
-	if d.tags == nil {
-		d.tags = map[string]bool{}
-	}
-	d.tags[h.UID()] = true
-	// log.Printf("Host: %#v, UID: %s", h, h.UID())
-	// d.tags[h.CanonicalURL().String()] = true
-	// d.tags[h.SSHURL().String()] = true
-}
-
-// IsDuplicate returns true if Host is a duplicate.
-func (d *Deduplicator) IsDuplicate(h Host) bool {
-	if d.tags == nil {
-		d.tags = map[string]bool{}
-	}
-	if dupe := d.tags[h.UID()]; dupe {
-		return true
-	}
-	return false
-}
-
-// FilterDuplicateHosts removes duplicate Hosts.
-func FilterDuplicateHosts(hosts []Host) []Host {
-	clean := []Host{}
-	d := &Deduplicator{}
-
-	for _, h := range hosts {
-		if d.IsDuplicate(h) {
-			continue
-		}
-		clean = append(clean, h)
-		d.Add(h)
-	}
-	return clean
+	UID() string                  // Unique ID of host
+	Name() string                 // Display name of host
+	Hostname() string             // Qualified hostname
+	Port() int                    // Port (22 by default)
+	SetPort(i int)                // Set the Port
+	Source() string               // Display name of source
+	Username() string             // Username if not default
+	SetUsername(n string)         // Set Username
+	CanonicalURL() *url.URL       // Canonical SSH URL
+	SSHURL() *url.URL             // ssh:// URL for this host
+	SFTPURL() *url.URL            // sftp:// URL for this host
+	SSHCmd(path string) *Command  // Command-line ssh command for this host
+	MoshCmd(path string) *Command // Command-line mosh command for this host
+	Meta() *Meta                  // Additional information about host
 }
 
 // type jsonHost struct {
@@ -106,38 +66,33 @@ type BaseHost struct {
 	source   string
 	username string
 	port     int
+	meta     Meta
 }
 
 // NewBaseHost creates a new BaseHost object.
 func NewBaseHost(name, hostname, source, username string, port int) *BaseHost {
-	return &BaseHost{name, hostname, source, username, port}
+	return &BaseHost{name: name, hostname: hostname, source: source, username: username, port: port}
 }
 
 // NewBaseHostFromURL creates a new BaseHost object.
 func NewBaseHostFromURL(u *url.URL) *BaseHost {
 	h := &BaseHost{
 		// name:     name,
-		hostname: u.Host,
+		hostname: u.Hostname(),
 		source:   "URL",
 		port:     22,
 	}
 	// Extract port from hostname
-	if i := strings.Index(u.Host, ":"); i > -1 {
-		h.hostname = u.Host[:i]
-		if j, err := strconv.Atoi(u.Host[i+1:]); err == nil {
-			h.port = j
-		}
+	if j, err := strconv.Atoi(u.Port()); err == nil {
+		h.port = j
 	}
 	if u.User != nil {
 		h.username = u.User.Username()
 	}
-	name := h.hostname
+	name := HostPort(h.hostname, h.port)
 	if h.username != "" {
 		name = h.username + "@" + name
 	}
-	if h.port != 22 {
-		name = fmt.Sprintf("%s:%d", name, h.port)
-	}
 	h.name = name
 	return h
 }
@@ -165,11 +120,13 @@ func NewBaseHostFromURL(u *url.URL) *BaseHost {
 // UID implements Host.
 func (h *BaseHost) UID() string { return UIDForHost(h) }
 
-// Name implements Host.
-func (h *BaseHost) Name() string { return h.name }
+// Name implements Host. Internationalised domain names are returned in
+// Unicode form.
+func (h *BaseHost) Name() string { return ToUnicode(h.name) }
 
-// Hostname implements Host.
-func (h *BaseHost) Hostname() string { return h.hostname }
+// Hostname implements Host. Internationalised domain names are returned
+// in ASCII (punycode) form.
+func (h *BaseHost) Hostname() string { return asciiHostname(h.hostname) }
 
 // Port implements Host.
 func (h *BaseHost) Port() int {
@@ -191,17 +148,24 @@ func (h *BaseHost) Username() string { return h.username }
 // SetUsername implemeents Host.
 func (h *BaseHost) SetUsername(n string) { h.username = n }
 
+// Meta implements Host.
+func (h *BaseHost) Meta() *Meta { return &h.meta }
+
+// base returns the BaseHost. It's promoted to types that embed BaseHost,
+// so sources can set the fields of their Hosts.
+func (h *BaseHost) base() *BaseHost { return h }
+
+// baseHoster is implemented by BaseHost and types that embed it.
+type baseHoster interface {
+	base() *BaseHost
+}
+
 // CanonicalURL implements Host.
 func (h *BaseHost) CanonicalURL() *url.URL {
-	u := &url.URL{Scheme: "ssh", Host: h.Hostname()}
+	u := &url.URL{Scheme: "ssh", Host: urlHost(h.Hostname(), h.Port())}
 	if h.Username() != "" {
 		u.User = url.User(h.Username())
 	}
-	if h.Port() == 22 {
-		u.Host = h.Hostname()
-	} else {
-		u.Host = fmt.Sprintf("%s:%d", h.Hostname(), h.Port())
-	}
 	return u
 }
 
@@ -212,59 +176,180 @@ func (h *BaseHost) SSHURL() *url.URL {
 	return u
 }
 
-// SFTPURL implements Host.
+// SFTPURL implements Host. The URL's path is the host's RemoteDir, if set.
 func (h *BaseHost) SFTPURL() *url.URL {
 	u := h.CanonicalURL()
 	u.Scheme = "sftp"
+	if dir := h.meta.RemoteDir; dir != "" {
+		u.Path = "/" + strings.TrimPrefix(dir, "/")
+	}
 	return u
 }
 
 // MoshCmd implements Host.
-func (h *BaseHost) MoshCmd(path string) string {
+func (h *BaseHost) MoshCmd(path string) *Command { return moshCmd(h, path, "") }
+
+// SSHCmd implements Host.
+func (h *BaseHost) SSHCmd(path string) *Command { return sshCmd(h, path, "") }
+
+// sshCmd returns an ssh command for Host h. If identityFile is set, it
+// is passed to ssh with -i.
+func sshCmd(h Host, path, identityFile string) *Command {
 	if path == "" {
-		path = "mosh"
-	}
-	cmd := path + " "
-	if h.Port() != 22 {
-		cmd += fmt.Sprintf("--ssh 'ssh -p %d' ", h.Port())
-	}
-	if h.Username() != "" {
-		cmd += h.Username() + "@"
+		path = "ssh"
 	}
-	cmd += h.Hostname()
-	return cmd
+	cmd := addSSHOptions(NewCommand(path), h.Port(), identityFile)
+	return cmd.AddTarget(userHost(h.Username(), h.Hostname()))
 }
 
-// SSHCmd implements Host.
-func (h *BaseHost) SSHCmd(path string) string {
+// moshCmd returns a mosh command for Host h. The port and identityFile
+// (if set) are passed to mosh's ssh command.
+func moshCmd(h Host, path, identityFile string) *Command {
 	if path == "" {
-		path = "ssh"
+		path = "mosh"
 	}
-	cmd := path + " "
-	if h.Port() != 22 {
-		cmd += fmt.Sprintf("-p %d ", h.Port())
+	cmd := NewCommand(path)
+	if ssh := addSSHOptions(NewCommand("ssh"), h.Port(), identityFile); len(ssh.Args) > 0 {
+		cmd.Add("--ssh", ssh.String())
 	}
-	if h.Username() != "" {
-		cmd += h.Username() + "@"
+	return cmd.AddTarget(userHost(h.Username(), h.Hostname()))
+}
+
+// addSSHOptions adds the port (unless it's 22) and private key (if set)
+// options to an ssh command.
+func addSSHOptions(cmd *Command, port int, identityFile string) *Command {
+	if port != 22 {
+		cmd.Add("-p", strconv.Itoa(port))
+	}
+	if identityFile != "" {
+		cmd.Add("-i", identityFile)
 	}
-	cmd += h.Hostname()
 	return cmd
 }
 
 // UIDForHost returns a UID for a Host.
 func UIDForHost(h Host) string {
-	uid := h.SSHURL().String()
-	if h.Port() != 22 && strings.Index(h.SSHURL().Host, ":") < 0 {
+	u := h.SSHURL()
+	uid := u.String()
+	if h.Port() != 22 && u.Port() == "" {
 		uid = fmt.Sprintf("%s:%d", uid, h.Port())
 	}
 
 	return fmt.Sprintf("%s||%s", h.Name(), uid)
 }
 
+// HostPort returns host, or "host:port" if port isn't the default.
+// IPv6 addresses are enclosed in square brackets if a port is added.
+func HostPort(host string, port int) string {
+	host = stripBrackets(host)
+	if port == 0 || port == 22 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// ParseQuery splits user input of the form [user@]host[:port] into its
+// components. IPv6 addresses may be entered as-is (without a port) or
+// enclosed in square brackets, e.g. "[2001:db8::5]:2222".
+// port is 0 if none is specified.
+func ParseQuery(s string) (user, host string, port int) {
+	if i := strings.Index(s, "@"); i > -1 {
+		user, s = s[:i], s[i+1:]
+	}
+
+	var p string
+	if strings.HasPrefix(s, "[") {
+		// [host] or [host]:port. Closing bracket may not have been
+		// typed yet.
+		if i := strings.Index(s, "]"); i > -1 {
+			host, p = s[1:i], strings.TrimPrefix(s[i+1:], ":")
+		} else {
+			host = s[1:]
+		}
+	} else if strings.Count(s, ":") == 1 {
+		// host:port. Unbracketed strings with more colons are IPv6
+		// addresses.
+		i := strings.Index(s, ":")
+		host, p = s[:i], s[i+1:]
+	} else {
+		host = s
+	}
+
+	if v, err := strconv.Atoi(p); err == nil {
+		port = v
+	}
+	return
+}
+
+// urlHost returns host (and port if not the default) in the format
+// expected by url.URL.Host, i.e. with IPv6 addresses in square brackets.
+func urlHost(host string, port int) string {
+	host = stripBrackets(host)
+	if port != 0 && port != 22 {
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// stripBrackets removes square brackets around an IPv6 address.
+func stripBrackets(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
 // IsValidHostname returns true if n is an IP address or hostname.
+//
+// Hostnames are validated according to RFC 1123 (with a trailing dot
+// permitted), except that underscores are also accepted, as they are
+// common in practice. Internationalised names are validated in their
+// ASCII form.
 func IsValidHostname(n string) bool {
 	if ip := net.ParseIP(n); ip != nil {
 		return true
 	}
-	return hostnameRegex.MatchString(n)
+	// IPv6 address with zone, e.g. fe80::1%en0
+	if i := strings.LastIndex(n, "%"); i > 0 && strings.Contains(n[:i], ":") {
+		return net.ParseIP(n[:i]) != nil
+	}
+
+	n, err := ToASCII(n)
+	if err != nil {
+		return false
+	}
+	n = strings.TrimSuffix(n, ".")
+	if n == "" || len(n) > maxHostnameLength {
+		return false
+	}
+
+	for _, label := range strings.Split(n, ".") {
+		if !isValidLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidLabel returns true if s is a valid hostname label.
+func isValidLabel(s string) bool {
+	if s == "" || len(s) > maxLabelLength {
+		return false
+	}
+	if s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
 }
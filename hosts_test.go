@@ -0,0 +1,245 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"net"
+	"net/url"
+	"testing"
+)
+
+var urlTests = []struct {
+	URL      string
+	Hostname string
+	Port     int
+	Name     string
+	SSHCmd   string
+}{
+	{"ssh://host.example.com", "host.example.com", 22, "host.example.com", "ssh host.example.com"},
+	{"ssh://bob@host.example.com:2222", "host.example.com", 2222, "bob@host.example.com:2222", "ssh -p 2222 bob@host.example.com"},
+	{"ssh://10.0.0.1:2222", "10.0.0.1", 2222, "10.0.0.1:2222", "ssh -p 2222 10.0.0.1"},
+	{"ssh://[::1]", "::1", 22, "::1", "ssh ::1"},
+	{"ssh://[2001:db8::5]:2222", "2001:db8::5", 2222, "[2001:db8::5]:2222", "ssh -p 2222 2001:db8::5"},
+	{"ssh://bob@[fe80::1]:2222", "fe80::1", 2222, "bob@[fe80::1]:2222", "ssh -p 2222 bob@fe80::1"},
+}
+
+// TestHostFromURL tests creating Hosts from URLs and back again.
+func TestHostFromURL(t *testing.T) {
+	for i, td := range urlTests {
+		u, err := url.Parse(td.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := NewBaseHostFromURL(u)
+		if h.Hostname() != td.Hostname {
+			t.Errorf("[%d] Bad hostname. Expected=%v, Got=%v", i+1, td.Hostname, h.Hostname())
+		}
+		if h.Port() != td.Port {
+			t.Errorf("[%d] Bad port. Expected=%v, Got=%v", i+1, td.Port, h.Port())
+		}
+		if h.Name() != td.Name {
+			t.Errorf("[%d] Bad name. Expected=%v, Got=%v", i+1, td.Name, h.Name())
+		}
+		if s := h.SSHURL().String(); s != td.URL {
+			t.Errorf("[%d] Bad URL. Expected=%v, Got=%v", i+1, td.URL, s)
+		}
+		if s := h.SSHCmd("").String(); s != td.SSHCmd {
+			t.Errorf("[%d] Bad command. Expected=%v, Got=%v", i+1, td.SSHCmd, s)
+		}
+		if s := h.UID(); s != td.Name+"||"+td.URL {
+			t.Errorf("[%d] Bad UID. Expected=%v, Got=%v", i+1, td.Name+"||"+td.URL, s)
+		}
+	}
+}
+
+var queryTests = []struct {
+	Query string
+	User  string
+	Host  string
+	Port  int
+}{
+	{"", "", "", 0},
+	{"host", "", "host", 0},
+	{"bob@host", "bob", "host", 0},
+	{"bob@host:2222", "bob", "host", 2222},
+	{"host:", "", "host", 0},
+	{"10.0.0.1:22", "", "10.0.0.1", 22},
+	{"fe80::1", "", "fe80::1", 0},
+	{"bob@2001:db8::5", "bob", "2001:db8::5", 0},
+	{"[2001:db8::5]:2222", "", "2001:db8::5", 2222},
+	{"bob@[2001:db8::5]", "bob", "2001:db8::5", 0},
+	{"[2001:db8::5", "", "2001:db8::5", 0},
+}
+
+// TestParseQuery tests parsing of user input.
+func TestParseQuery(t *testing.T) {
+	for i, td := range queryTests {
+		user, host, port := ParseQuery(td.Query)
+		if user != td.User || host != td.Host || port != td.Port {
+			t.Errorf("[%d] Expected=(%q, %q, %d), Got=(%q, %q, %d): %s",
+				i+1, td.User, td.Host, td.Port, user, host, port, td.Query)
+		}
+	}
+}
+
+var idnaTests = []struct {
+	Unicode string
+	ASCII   string
+}{
+	{"localhost", "localhost"},
+	{"bücher.example", "xn--bcher-kva.example"},
+	{"münchen.de", "xn--mnchen-3ya.de"},
+	{"例え.テスト", "xn--r8jz45g.xn--zckzah"},
+	{"host.日本語.jp", "host.xn--wgv71a119e.jp"},
+}
+
+// TestIDNA tests conversion of internationalised domain names.
+func TestIDNA(t *testing.T) {
+	for i, td := range idnaTests {
+		s, err := ToASCII(td.Unicode)
+		if err != nil {
+			t.Errorf("[%d] ToASCII failed: %v", i+1, err)
+			continue
+		}
+		if s != td.ASCII {
+			t.Errorf("[%d] Expected=%v, Got=%v", i+1, td.ASCII, s)
+		}
+		if s := ToUnicode(td.ASCII); s != td.Unicode {
+			t.Errorf("[%d] Expected=%v, Got=%v", i+1, td.Unicode, s)
+		}
+	}
+
+	// Names are mapped before conversion
+	for _, name := range []string{"Bücher.example", "bücher。example"} {
+		if s, err := ToASCII(name); err != nil || s != "xn--bcher-kva.example" {
+			t.Errorf("Bad mapping for %q: %q (err=%v)", name, s, err)
+		}
+	}
+	if _, err := ToASCII("bü cher.example"); err == nil {
+		t.Error("Accepted invalid name")
+	}
+
+	// Display Unicode, connect with ASCII
+	h := NewBaseHost("bücher.example", "bücher.example", "", "", 22)
+	if h.Name() != "bücher.example" {
+		t.Errorf("Bad name: %s", h.Name())
+	}
+	if s := h.SSHCmd("").String(); s != "ssh xn--bcher-kva.example" {
+		t.Errorf("Bad command: %s", s)
+	}
+	h = NewBaseHost("xn--bcher-kva.example", "xn--bcher-kva.example", "", "", 22)
+	if h.Name() != "bücher.example" {
+		t.Errorf("Bad name: %s", h.Name())
+	}
+}
+
+var commandTests = []struct {
+	Host *BaseHost
+	SSH  string
+	Mosh string
+}{
+	{NewBaseHost("host", "host", "", "", 22), "ssh host", "mosh host"},
+	{NewBaseHost("host", "host", "", "bob", 2222),
+		"ssh -p 2222 bob@host", "mosh --ssh 'ssh -p 2222' bob@host"},
+	// Shell metacharacters
+	{NewBaseHost("host", "host; rm -rf ~", "", "", 22),
+		"ssh 'host; rm -rf ~'", "mosh 'host; rm -rf ~'"},
+	{NewBaseHost("host", "host", "", "it's me", 22),
+		`ssh 'it'"'"'s me@host'`, `mosh 'it'"'"'s me@host'`},
+	{NewBaseHost("host", "$(reboot)", "", "", 22), "ssh '$(reboot)'", "mosh '$(reboot)'"},
+	// Option injection
+	{NewBaseHost("host", "-oProxyCommand=reboot", "", "", 22),
+		"ssh -- -oProxyCommand=reboot", "mosh -- -oProxyCommand=reboot"},
+}
+
+// TestShellCommands tests that generated commands are correctly quoted.
+func TestShellCommands(t *testing.T) {
+	for i, td := range commandTests {
+		if s := td.Host.SSHCmd("").String(); s != td.SSH {
+			t.Errorf("[%d] Expected=%v, Got=%v", i+1, td.SSH, s)
+		}
+		if s := td.Host.MoshCmd("").String(); s != td.Mosh {
+			t.Errorf("[%d] Expected=%v, Got=%v", i+1, td.Mosh, s)
+		}
+	}
+
+	// Configured program is not quoted
+	h := NewBaseHost("host", "host", "", "", 22)
+	if s := h.SSHCmd("/usr/local/bin/ssh -A").String(); s != "/usr/local/bin/ssh -A host" {
+		t.Errorf("Bad command: %s", s)
+	}
+}
+
+// TestActions tests parsing and expansion of user-defined actions.
+func TestActions(t *testing.T) {
+	actions := ParseActions([]string{
+		"HOME=/Users/bob",
+		"ACTION_RSYNC=rsync -avz . {login}:",
+		"ACTION_RSYNC_MOD=cmd+alt",
+		"ACTION_WEB_UI=https://{hostname}:8443/?user={user}",
+		"ACTION_EMPTY=",
+	})
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d: %v", len(actions), actions)
+	}
+
+	h := NewBaseHost("web", "web.example.com", "", "o'brien", 22)
+	rsync, web := actions[0], actions[1]
+	if rsync.Name != "Rsync" || rsync.Modifier != "cmd+alt" || rsync.IsURL() {
+		t.Errorf("Bad action: %+v", rsync)
+	}
+	if s := rsync.Expand(h); s != `rsync -avz . 'o'"'"'brien@web.example.com':` {
+		t.Errorf("Bad command: %s", s)
+	}
+	if web.Name != "Web UI" || web.Modifier != "" || !web.IsURL() {
+		t.Errorf("Bad action: %+v", web)
+	}
+	if s := web.Expand(h); s != "https://web.example.com:8443/?user=o%27brien" {
+		t.Errorf("Bad URL: %s", s)
+	}
+
+	// Values are escaped for the part of the URL they're in
+	tests := []struct {
+		tpl  string
+		host Host
+		x    string
+	}{
+		{"https://{hostname}:8443/?user={user}", NewBaseHost("web", "web", "", "bob&admin=1", 22),
+			"https://web:8443/?user=bob%26admin%3D1"},
+		{"https://{hostname}:8443/", NewBaseHost("v6", "fe80::1", "", "", 22), "https://[fe80::1]:8443/"},
+		{"https://{ip}/hosts/{name}?q={name}#{user}", NewBaseHost("a/b c", "2001:db8::5", "", "x?y", 22),
+			"https://[2001:db8::5]/hosts/a%2Fb%20c?q=a%2Fb+c#x%3Fy"},
+		{"vnc://{user}@{host}", NewBaseHost("h", "h.example.com", "", "a@b", 22), "vnc://a%40b@h.example.com"},
+		{"vnc://{login}", NewBaseHost("h", "h.example.com", "", "a@b", 22), "vnc://a%40b@h.example.com"},
+		{"vnc://{login}", NewBaseHost("h", "h.example.com", "", "", 22), "vnc://h.example.com"},
+		{"vnc://{login}/", NewBaseHost("v6", "fe80::1", "", "bob", 22), "vnc://bob@[fe80::1]/"},
+		{"https://example.com/?to={login}", NewBaseHost("h", "h", "", "bob", 22), "https://example.com/?to=bob%40h"},
+	}
+	for _, td := range tests {
+		h := td.host
+		if ip := stripBrackets(h.Hostname()); net.ParseIP(ip) != nil {
+			h.Meta().AddIP(ip)
+		}
+		if s := (Action{Template: td.tpl}).Expand(h); s != td.x {
+			t.Errorf("Bad URL for %q. Expected=%q, Got=%q", td.tpl, td.x, s)
+		}
+	}
+	// Acronyms stay upper-case in names
+	names := map[string]string{
+		"OPEN_WEB_UI": "Open Web UI",
+		"VNC":         "VNC",
+		"COPY_IP":     "Copy IP",
+		"rsync_home":  "Rsync Home",
+	}
+	for key, x := range names {
+		if s := actionName(key); s != x {
+			t.Errorf("Bad name for %q. Expected=%q, Got=%q", key, x, s)
+		}
+	}
+}
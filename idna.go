@@ -0,0 +1,56 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ToASCII converts an internationalised domain name to its ASCII
+// (punycode) form, e.g. "bücher.example" to "xn--bcher-kva.example".
+// ASCII names are returned unchanged.
+func ToASCII(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+	return idna.Lookup.ToASCII(name)
+}
+
+// ToUnicode converts the punycode labels of a domain name to Unicode,
+// e.g. "xn--bcher-kva.example" to "bücher.example". Labels that aren't
+// valid punycode are left as they are.
+func ToUnicode(name string) string {
+	if !strings.Contains(strings.ToLower(name), "xn--") {
+		return name
+	}
+	s, _ := idna.Lookup.ToUnicode(name)
+	return s
+}
+
+// asciiHostname returns the ASCII form of name, or name itself if it
+// can't be converted.
+func asciiHostname(name string) string {
+	if s, err := ToASCII(name); err == nil {
+		return s
+	}
+	return name
+}
+
+// isASCII returns true if s contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
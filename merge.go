@@ -0,0 +1,252 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// MergedHost is a Host that was found in several sources, possibly under
+// different names, e.g. "web1" in ~/.ssh/config, "web1.example.com" in
+// known_hosts and "10.0.0.7" in /etc/hosts.
+//
+// The connection details (name, hostname, port, username etc.) are those
+// of the highest-priority Host. Metadata is merged from all Hosts, and
+// the merged private key and remote directory are used in commands and
+// URLs if that Host doesn't set its own.
+type MergedHost struct {
+	Host  // Highest-priority Host
+	hosts []Host
+	meta  Meta
+}
+
+// Meta implements Host. It returns the combined metadata of all Hosts.
+func (h *MergedHost) Meta() *Meta { return &h.meta }
+
+// SFTPURL implements Host. If the highest-priority Host has no remote
+// directory, the one from the merged metadata is used.
+func (h *MergedHost) SFTPURL() *url.URL {
+	u := h.Host.SFTPURL()
+	if dir := h.meta.RemoteDir; dir != "" && h.Host.Meta().RemoteDir == "" {
+		u.Path = "/" + strings.TrimPrefix(dir, "/")
+	}
+	return u
+}
+
+// SSHCmd implements Host. If the highest-priority Host has no private
+// key, the one from the merged metadata is passed to ssh.
+func (h *MergedHost) SSHCmd(path string) *Command {
+	if key := h.mergedKey(); key != "" {
+		return sshCmd(h, path, key)
+	}
+	return h.Host.SSHCmd(path)
+}
+
+// MoshCmd implements Host. If the highest-priority Host has no private
+// key, the one from the merged metadata is passed to mosh's ssh.
+func (h *MergedHost) MoshCmd(path string) *Command {
+	if key := h.mergedKey(); key != "" {
+		return moshCmd(h, path, key)
+	}
+	return h.Host.MoshCmd(path)
+}
+
+// mergedKey returns the private key from the merged metadata if the
+// highest-priority Host doesn't have its own.
+func (h *MergedHost) mergedKey() string {
+	if h.Host.Meta().IdentityFile != "" {
+		return ""
+	}
+	return h.meta.IdentityFile
+}
+
+// Hosts returns the merged Hosts in order of priority.
+func (h *MergedHost) Hosts() []Host { return h.hosts }
+
+// Sources returns the names of all sources the Host was found in.
+func (h *MergedHost) Sources() []string {
+	var l []string
+	for _, host := range h.hosts {
+		l = appendUnique(l, host.Source())
+	}
+	return l
+}
+
+// Names returns all the names of the Host.
+func (h *MergedHost) Names() []string {
+	var l []string
+	for _, host := range h.hosts {
+		l = appendUnique(l, host.Name())
+	}
+	return l
+}
+
+// newMergedHost creates a MergedHost from priority-sorted hosts.
+func newMergedHost(hosts []Host) *MergedHost {
+	h := &MergedHost{Host: hosts[0], hosts: hosts}
+	for _, host := range hosts {
+		h.meta.Merge(host.Meta())
+	}
+	h.meta.Action = hosts[0].Meta().Action
+	return h
+}
+
+// hostGroup is a set of Hosts with the same endpoint.
+type hostGroup struct {
+	hosts    []Host
+	username string
+	merged   bool // Group has been merged into another
+}
+
+// compatible returns true if username doesn't conflict with the group's.
+func (g *hostGroup) compatible(username string) bool {
+	return g.username == "" || username == "" || g.username == username
+}
+
+// add adds hosts to the group.
+func (g *hostGroup) add(hosts ...Host) {
+	for _, h := range hosts {
+		if g.username == "" {
+			g.username = h.Username()
+		}
+		g.hosts = append(g.hosts, h)
+	}
+}
+
+// MergeHosts combines Hosts that point to the same endpoint into a single
+// MergedHost. Hosts are considered the same if they have the same
+// hostname or IP address (see Meta.IPs) and port. Hosts with different
+// usernames are not merged, but a Host without a username may be merged
+// with one that has a username.
+//
+// hosts must be sorted by priority. The order is preserved, and Hosts
+// with no duplicates are returned as-is.
+func MergeHosts(hosts []Host) []Host { return mergeHosts(hosts, addressKeys) }
+
+// mergeHosts combines Hosts that share any of the keys returned by keyFunc.
+func mergeHosts(hosts []Host, keyFunc func(Host) []string) []Host {
+	var (
+		groups []*hostGroup
+		index  = map[string][]int{} // endpoint -> groups
+	)
+
+	for _, h := range hosts {
+		keys := keyFunc(h)
+
+		// Find existing groups for this endpoint
+		var matches []int
+		for _, k := range keys {
+			for _, i := range index[k] {
+				if !groups[i].merged && groups[i].compatible(h.Username()) {
+					matches = appendInt(matches, i)
+				}
+			}
+		}
+
+		if len(matches) == 0 {
+			groups = append(groups, &hostGroup{})
+			matches = []int{len(groups) - 1}
+		}
+
+		// Host may link several groups, e.g. a hostname from one
+		// source and an IP address from another.
+		first := minInt(matches)
+		g := groups[first]
+		for _, i := range matches {
+			if i == first || !g.compatible(groups[i].username) {
+				continue
+			}
+			g.add(groups[i].hosts...)
+			groups[i].merged = true
+			for k, l := range index {
+				for j, n := range l {
+					if n == i {
+						l[j] = first
+					}
+				}
+				index[k] = l
+			}
+		}
+		g.add(h)
+		for _, k := range keys {
+			index[k] = appendInt(index[k], first)
+		}
+	}
+
+	merged := make([]Host, 0, len(groups))
+	for _, g := range groups {
+		if g.merged {
+			continue
+		}
+		if len(g.hosts) == 1 {
+			merged = append(merged, g.hosts[0])
+			continue
+		}
+		sortByPriority(g.hosts, hosts)
+		merged = append(merged, newMergedHost(g.hosts))
+	}
+	return merged
+}
+
+// hostnameKeys returns the hostname and port of a Host.
+func hostnameKeys(h Host) []string {
+	name := strings.TrimSuffix(strings.ToLower(stripBrackets(h.Hostname())), ".")
+	if name == "" {
+		return nil
+	}
+	return []string{HostPort(name, h.Port())}
+}
+
+// addressKeys returns the hostname and port and the known IP addresses
+// and port of a Host.
+func addressKeys(h Host) []string {
+	keys := hostnameKeys(h)
+	for _, ip := range h.Meta().IPs {
+		keys = appendUnique(keys, HostPort(strings.ToLower(ip), h.Port()))
+	}
+	return keys
+}
+
+// sortByPriority sorts group into the same order as hosts, which is
+// sorted by priority.
+func sortByPriority(group, hosts []Host) {
+	pos := make(map[Host]int, len(group))
+	for _, h := range group {
+		pos[h] = -1
+	}
+	for i, h := range hosts {
+		if _, ok := pos[h]; ok {
+			pos[h] = i
+		}
+	}
+	sort.SliceStable(group, func(i, j int) bool { return pos[group[i]] < pos[group[j]] })
+}
+
+// appendInt appends i to l if l doesn't already contain it.
+func appendInt(l []int, i int) []int {
+	for _, n := range l {
+		if n == i {
+			return l
+		}
+	}
+	return append(l, i)
+}
+
+// minInt returns the smallest int in l.
+func minInt(l []int) int {
+	n := l[0]
+	for _, i := range l[1:] {
+		if i < n {
+			n = i
+		}
+	}
+	return n
+}
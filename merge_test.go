@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMergeHosts tests merging of hosts with the same endpoint.
+func TestMergeHosts(t *testing.T) {
+	newHost := func(name, hostname, source, user string, port int, ips ...string) *BaseHost {
+		h := NewBaseHost(name, hostname, source, user, port)
+		h.meta.AddIP(ips...)
+		return h
+	}
+
+	config := newHost("web1", "web1.corp.example.com", "~/.ssh/config", "deploy", 22)
+	config.meta.AddTag("prod")
+	hosts := []Host{
+		config,
+		// IP first, so it's only linked to web1 by /etc/hosts
+		newHost("10.0.0.7", "10.0.0.7", "known_hosts", "", 22),
+		newHost("web1.corp.example.com", "web1.corp.example.com", "known_hosts", "", 22, "10.0.0.7"),
+		newHost("db", "db.corp.example.com", "known_hosts", "", 22),
+		// Different port
+		newHost("web1.corp.example.com:2222", "web1.corp.example.com", "known_hosts", "", 2222),
+		// Different user
+		newHost("root@web1.corp.example.com", "web1.corp.example.com", "history", "root", 22),
+		newHost("web1", "web1", "/etc/hosts", "", 22, "10.0.0.7"),
+	}
+
+	merged := MergeHosts(hosts)
+	if len(merged) != 4 {
+		t.Fatalf("Expected 4 hosts, Got=%v", merged)
+	}
+
+	m, ok := merged[0].(*MergedHost)
+	if !ok {
+		t.Fatalf("Expected MergedHost, Got=%#v", merged[0])
+	}
+	if m.Name() != "web1" || m.Username() != "deploy" || m.Source() != "~/.ssh/config" {
+		t.Errorf("Wrong connection details: %s, %s, %s", m.Name(), m.Username(), m.Source())
+	}
+	if x := []string{"~/.ssh/config", "known_hosts", "/etc/hosts"}; !reflect.DeepEqual(m.Sources(), x) {
+		t.Errorf("Expected=%v, Got=%v", x, m.Sources())
+	}
+	if x := []string{"web1", "10.0.0.7", "web1.corp.example.com"}; !reflect.DeepEqual(m.Names(), x) {
+		t.Errorf("Expected=%v, Got=%v", x, m.Names())
+	}
+	if x := []string{"prod"}; !reflect.DeepEqual(m.Meta().Tags, x) {
+		t.Errorf("Expected=%v, Got=%v", x, m.Meta().Tags)
+	}
+	if x := []string{"10.0.0.7"}; !reflect.DeepEqual(m.Meta().IPs, x) {
+		t.Errorf("Expected=%v, Got=%v", x, m.Meta().IPs)
+	}
+
+	for i, name := range []string{"db", "web1.corp.example.com:2222", "root@web1.corp.example.com"} {
+		h := merged[i+1]
+		if _, ok := h.(*MergedHost); ok || h.Name() != name {
+			t.Errorf("[%d] Expected=%q, Got=%#v", i+1, name, h)
+		}
+	}
+}
+
+// TestMergedHostCommands tests that the key and remote directory of
+// lower-priority Hosts are used.
+func TestMergedHostCommands(t *testing.T) {
+	config := NewBaseHost("web1", "web1.example.com", "~/.ssh/config", "deploy", 22)
+	site := NewBaseHost("Web", "web1.example.com", "FileZilla", "", 22)
+	site.meta.RemoteDir = "/srv/www"
+	site.meta.IdentityFile = "/keys/web"
+
+	m := newMergedHost([]Host{config, site})
+	if x, v := "ssh -i /keys/web deploy@web1.example.com", m.SSHCmd("").String(); v != x {
+		t.Errorf("Bad ssh command. Expected=%q, Got=%q", x, v)
+	}
+	if x, v := "mosh --ssh 'ssh -i /keys/web' deploy@web1.example.com", m.MoshCmd("").String(); v != x {
+		t.Errorf("Bad mosh command. Expected=%q, Got=%q", x, v)
+	}
+	if x, v := "sftp://deploy@web1.example.com/srv/www", m.SFTPURL().String(); v != x {
+		t.Errorf("Bad SFTP URL. Expected=%q, Got=%q", x, v)
+	}
+
+	// The highest-priority Host's own settings win
+	config.meta.IdentityFile = "/keys/deploy"
+	config.meta.RemoteDir = "/home/deploy"
+	m = newMergedHost([]Host{config, site})
+	if x, v := "ssh deploy@web1.example.com", m.SSHCmd("").String(); v != x {
+		t.Errorf("Bad ssh command. Expected=%q, Got=%q", x, v)
+	}
+	if x, v := "sftp://deploy@web1.example.com/home/deploy", m.SFTPURL().String(); v != x {
+		t.Errorf("Bad SFTP URL. Expected=%q, Got=%q", x, v)
+	}
+}
@@ -0,0 +1,141 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"sort"
+	"strings"
+)
+
+// Meta is additional information about a Host. Sources populate whichever
+// fields they know about; all fields are optional.
+type Meta struct {
+	Tags         []string          // Labels, e.g. group names
+	Description  string            // Free-text description or notes
+	IPs          []string          // Known IP addresses of the host
+	IdentityFile string            // Private key to connect with
+	JumpHost     string            // Host to connect via (ssh -J)
+	RemoteDir    string            // Initial directory for SFTP connections
+	Action       string            // Default action, e.g. "sftp" (empty = ssh)
+	Values       map[string]string // Arbitrary key/value pairs
+}
+
+// Default actions for Meta.Action.
+const (
+	ActionSSH  = ""
+	ActionSFTP = "sftp"
+)
+
+// AddTag adds tags to Meta, ignoring empty and duplicate tags.
+func (m *Meta) AddTag(tags ...string) {
+	m.Tags = appendUnique(m.Tags, tags...)
+}
+
+// AddIP adds IP addresses to Meta, ignoring empty and duplicate addresses.
+func (m *Meta) AddIP(ips ...string) {
+	m.IPs = appendUnique(m.IPs, ips...)
+}
+
+// Get returns the arbitrary value for key or an empty string.
+func (m *Meta) Get(key string) string {
+	return m.Values[key]
+}
+
+// Set sets an arbitrary value. An empty value deletes key.
+func (m *Meta) Set(key, value string) {
+	if value == "" {
+		delete(m.Values, key)
+		return
+	}
+	if m.Values == nil {
+		m.Values = map[string]string{}
+	}
+	m.Values[key] = value
+}
+
+// Merge adds the tags, IPs and values from other to m. Fields already
+// set on m take precedence. Action is not merged, as it's a property of
+// the source a Host is from.
+func (m *Meta) Merge(other *Meta) {
+	m.AddTag(other.Tags...)
+	m.AddIP(other.IPs...)
+	if m.Description == "" {
+		m.Description = other.Description
+	}
+	if m.IdentityFile == "" {
+		m.IdentityFile = other.IdentityFile
+	}
+	if m.JumpHost == "" {
+		m.JumpHost = other.JumpHost
+	}
+	if m.RemoteDir == "" {
+		m.RemoteDir = other.RemoteDir
+	}
+	for k, v := range other.Values {
+		if m.Get(k) == "" {
+			m.Set(k, v)
+		}
+	}
+}
+
+// Vars returns the metadata as a map of (Alfred) variables. Only fields
+// that are set are included. Arbitrary values are prefixed with "meta_".
+func (m *Meta) Vars() map[string]string {
+	vars := map[string]string{}
+	if len(m.Tags) > 0 {
+		vars["tags"] = strings.Join(m.Tags, ",")
+	}
+	if m.Description != "" {
+		vars["description"] = m.Description
+	}
+	if len(m.IPs) > 0 {
+		vars["ips"] = strings.Join(m.IPs, ",")
+	}
+	if m.IdentityFile != "" {
+		vars["identity_file"] = m.IdentityFile
+	}
+	if m.JumpHost != "" {
+		vars["jump_host"] = m.JumpHost
+	}
+	if m.RemoteDir != "" {
+		vars["remote_dir"] = m.RemoteDir
+	}
+	if m.Action != "" {
+		vars["default_action"] = m.Action
+	}
+	keys := make([]string, 0, len(m.Values))
+	for k := range m.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vars["meta_"+k] = m.Values[k]
+	}
+	return vars
+}
+
+// appendUnique appends non-empty values to l that it doesn't already contain.
+func appendUnique(l []string, values ...string) []string {
+	for _, s := range values {
+		if s == "" {
+			continue
+		}
+		dupe := false
+		for _, x := range l {
+			if x == s {
+				dupe = true
+				break
+			}
+		}
+		if !dupe {
+			l = append(l, s)
+		}
+	}
+	return l
+}
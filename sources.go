@@ -9,11 +9,17 @@
 package ssh
 
 import (
+	"context"
 	"log"
 	"os"
 	"sort"
+	"sync"
+	"time"
 )
 
+// DefaultSourceTimeout is how long a source may take to load its Hosts.
+const DefaultSourceTimeout = 3 * time.Second
+
 // Source provides Hosts.
 type Source interface {
 	Name() string  // Display name of the source
@@ -21,24 +27,113 @@ type Source interface {
 	Priority() int // Priority (lower number = higher priority)
 }
 
+// ContextSource is a Source that can stop loading its Hosts when a
+// Context is cancelled. Slow sources, e.g. ones that run a command or
+// make network requests, should implement it.
+type ContextSource interface {
+	Source
+	HostsContext(ctx context.Context) ([]Host, error)
+}
+
+// TimeoutSource is a Source that needs a different time limit to the
+// one passed to Sources.Load, e.g. one that runs a slow command.
+type TimeoutSource interface {
+	Source
+	Timeout() time.Duration
+}
+
 // Sources is a priority-sorted list of Sources.
 type Sources []Source
 
-// Hosts returns all hosts from all sources.
+// Hosts returns all hosts from all sources. Duplicates are removed
+// according to DefaultDedupStrategy.
 func (sl Sources) Hosts() []Host {
-	var hosts = []Host{}
-	// var seen = map[string]bool{}
+	return sl.FilteredHosts(NewDuplicateFilter(DefaultDedupStrategy))
+}
+
+// FilteredHosts returns all hosts from all sources with duplicates
+// removed (or merged) by DuplicateFilter d.
+func (sl Sources) FilteredHosts(d DuplicateFilter) []Host {
+	hosts, _ := sl.Load(context.Background(), DefaultSourceTimeout, d)
+	return hosts
+}
+
+// Load loads Hosts from all sources in parallel and removes (or merges)
+// duplicates with DuplicateFilter d. Each source has timeout to load its
+// Hosts (0 = no limit), and the hosts from any that take longer are
+// ignored. Hosts are returned in order of source priority, followed by
+// the sources that timed out.
+func (sl Sources) Load(ctx context.Context, timeout time.Duration, d DuplicateFilter) ([]Host, []Source) {
+	var (
+		hosts    = []Host{}
+		timedOut []Source
+		results  = make([][]Host, len(sl))
+		failed   = make([]bool, len(sl))
+		wg       sync.WaitGroup
+	)
 	sort.Sort(sl)
-	for _, s := range sl {
-		hosts = append(hosts, s.Hosts()...)
+
+	for i, s := range sl {
+		wg.Add(1)
+		go func(i int, s Source) {
+			defer wg.Done()
+			ctx, timeout := ctx, timeout
+			if ts, ok := s.(TimeoutSource); ok && ts.Timeout() > 0 {
+				timeout = ts.Timeout()
+			}
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			hosts, err := loadSource(ctx, s)
+			if err != nil {
+				log.Printf("[source/%s] error: %v", s.Name(), err)
+				failed[i] = ctx.Err() == context.DeadlineExceeded
+				return
+			}
+			results[i] = hosts
+			log.Printf("[source/%s] %d host(s) loaded in %v", s.Name(), len(hosts), time.Since(start))
+		}(i, s)
+	}
+	wg.Wait()
+
+	for i, s := range sl {
+		if failed[i] {
+			timedOut = append(timedOut, s)
+		}
+		hosts = append(hosts, results[i]...)
+	}
+	if len(timedOut) > 0 {
+		log.Printf("%d source(s) timed out after %v", len(timedOut), timeout)
 	}
+
 	i := len(hosts)
-	hosts = FilterDuplicateHosts(hosts)
-	dupes := i - len(hosts)
-	if dupes > 0 {
-		log.Printf("%d duplicate(s) ignored", dupes)
+	hosts = d.Filter(hosts)
+	if dupes := i - len(hosts); dupes > 0 {
+		log.Printf("%d duplicate(s) removed or merged", dupes)
+	}
+	return hosts, timedOut
+}
+
+// loadSource returns the Hosts from Source s or the Context's error if
+// it is cancelled first. As a plain Source can't be stopped, its Hosts()
+// method keeps running in the background until it returns.
+func loadSource(ctx context.Context, s Source) ([]Host, error) {
+	if cs, ok := s.(ContextSource); ok {
+		return cs.HostsContext(ctx)
+	}
+
+	ch := make(chan []Host, 1)
+	go func() { ch <- s.Hosts() }()
+	select {
+	case hosts := <-ch:
+		return hosts, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	return hosts
 }
 
 // Len implements sort.Interface.
@@ -63,6 +158,7 @@ func DefaultSources() Sources {
 
 type baseSource struct {
 	Filepath string
+	Cache    *HostCache // Cache for parsed Hosts (optional)
 	name     string
 	hosts    []Host
 	priority int
@@ -73,3 +169,52 @@ func (s *baseSource) Name() string { return s.name }
 
 // Priority implements Source.
 func (s *baseSource) Priority() int { return s.priority }
+
+// loadHosts returns the Hosts from the source's file, from the cache if
+// the file hasn't changed. Otherwise parse is called to read the file. It
+// returns the Hosts and the paths of any other files that were read.
+func (s *baseSource) loadHosts(kind string, parse func() ([]Host, []string)) []Host {
+	hosts, ok := s.Cache.Load(kind, s.Filepath)
+	if ok {
+		log.Printf("[source/load/%s] %d cached host(s) for '%s'", kind, len(hosts), s.Name())
+	} else {
+		var files []string
+		hosts, files = parse()
+		log.Printf("[source/load/%s] %d host(s) in '%s'", kind, len(hosts), s.Name())
+		if err := s.Cache.Store(kind, s.Filepath, hosts, files...); err != nil && !os.IsNotExist(err) {
+			log.Printf("[cache/%s] error caching hosts: %v", kind, err)
+		}
+	}
+
+	for _, h := range hosts {
+		if hb, ok := h.(baseHoster); ok {
+			b := hb.base()
+			b.source = s.Name()
+			b.meta.Set("file", s.Filepath)
+		}
+	}
+	return hosts
+}
+
+// loadCachedData returns the data cached under key, e.g. an API response,
+// calling fetch to get new data if the cached data are older than maxAge.
+// If fetch fails, the stale data are returned instead, so sources still
+// work while offline. The error is only returned if nothing is cached.
+func (s *baseSource) loadCachedData(kind, key string, maxAge time.Duration, fetch func() ([]byte, error)) ([]byte, error) {
+	data, fresh := s.Cache.LoadData(kind, key, maxAge)
+	if fresh {
+		return data, nil
+	}
+	v, err := fetch()
+	if err != nil {
+		if data == nil {
+			return nil, err
+		}
+		log.Printf("[source/%s/%s] using stale data: %v", kind, s.Name(), err)
+		return data, nil
+	}
+	if err := s.Cache.StoreData(kind, key, v); err != nil {
+		log.Printf("[cache/%s] error caching data: %v", kind, err)
+	}
+	return v, nil
+}
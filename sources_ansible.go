@@ -0,0 +1,649 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Names of Ansible's implicit groups.
+const (
+	ansibleAll       = "all"
+	ansibleUngrouped = "ungrouped"
+)
+
+// Maximum number of hosts generated by a host pattern such as
+// "web[001:999].example.com" or "r[1:100]n[1:100]".
+const maxPatternHosts = 10000
+
+// ansibleGroup is a group in an Ansible inventory.
+type ansibleGroup struct {
+	name     string
+	vars     map[string]string
+	hosts    []string
+	children []string
+}
+
+// ansibleHost is a host in an Ansible inventory.
+type ansibleHost struct {
+	name string
+	vars map[string]string
+}
+
+// ansibleInventory is a parsed Ansible inventory.
+type ansibleInventory struct {
+	groups map[string]*ansibleGroup
+	hosts  map[string]*ansibleHost
+	order  []string // Hostnames in the order they were found
+}
+
+func newAnsibleInventory() *ansibleInventory {
+	return &ansibleInventory{groups: map[string]*ansibleGroup{}, hosts: map[string]*ansibleHost{}}
+}
+
+// group returns the named group, creating it if necessary.
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &ansibleGroup{name: name, vars: map[string]string{}}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+// addHost adds a host to group (if not empty) and sets its vars.
+func (inv *ansibleInventory) addHost(group, name string, vars map[string]string) {
+	h, ok := inv.hosts[name]
+	if !ok {
+		h = &ansibleHost{name: name, vars: map[string]string{}}
+		inv.hosts[name] = h
+		inv.order = append(inv.order, name)
+	}
+	for k, v := range vars {
+		h.vars[k] = v
+	}
+	if group != "" {
+		g := inv.group(group)
+		g.hosts = appendUnique(g.hosts, name)
+	}
+}
+
+// AnsibleSource implements Source for an Ansible inventory in INI or YAML
+// format, or a directory of inventory files.
+//
+// The connection details of hosts are taken from the ansible_host,
+// ansible_port and ansible_user variables, which may be set on the host,
+// its groups (including group_vars and host_vars directories next to
+// the inventory) or "all". The names of a host's groups are its tags.
+type AnsibleSource struct {
+	baseSource
+}
+
+// NewAnsibleSource creates a new AnsibleSource for an inventory file or
+// directory.
+func NewAnsibleSource(path, name string, priority int) *AnsibleSource {
+	s := &AnsibleSource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *AnsibleSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("ansible", func() ([]Host, []string) {
+			hosts, files := readAnsibleInventory(s.Filepath)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, files
+		})
+	}
+	return s.hosts
+}
+
+// readAnsibleInventory reads hosts from an inventory file or directory.
+// It also returns the other files and directories that were read.
+func readAnsibleInventory(path string) ([]*BaseHost, []string) {
+	var (
+		inv   = newAnsibleInventory()
+		files []string
+		dir   = filepath.Dir(path)
+	)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		log.Printf("[ansible/%s] %v", path, err)
+		return nil, nil
+	}
+
+	paths := []string{path}
+	if fi.IsDir() {
+		dir = path
+		paths = ansibleInventoryFiles(path)
+		files = append(files, paths...)
+	}
+	for _, p := range paths {
+		if err := inv.parseFile(p); err != nil {
+			log.Printf("[ansible/%s] %v", p, err)
+		}
+	}
+	files = append(files, inv.loadVarsDirs(dir)...)
+
+	return inv.baseHosts(), files
+}
+
+// ansibleInventoryFiles returns the inventory files in a directory.
+func ansibleInventoryFiles(dir string) []string {
+	var paths []string
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Printf("[ansible/%s] %v", dir, err)
+		return nil
+	}
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".retry", ".cfg", ".md", ".txt", ".orig", ".pyc", ".py", ".sh":
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths
+}
+
+// parseFile parses an INI or YAML inventory file.
+func (inv *ansibleInventory) parseFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".json":
+		return inv.parseYAML(data)
+	}
+	return inv.parseINI(data)
+}
+
+// parseINI parses an inventory in INI format.
+func (inv *ansibleInventory) parseINI(data []byte) error {
+	var (
+		section = ansibleUngrouped
+		kind    = "hosts" // or "vars" or "children"
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		n       int
+	)
+
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		// Section header
+		if line[0] == '[' {
+			i := strings.Index(line, "]")
+			if i < 0 {
+				return fmt.Errorf("invalid section on line %d: %s", n, line)
+			}
+			section, kind = line[1:i], "hosts"
+			if j := strings.LastIndex(section, ":"); j > 0 {
+				section, kind = section[:j], section[j+1:]
+			}
+			inv.group(section)
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			k, v := splitINIVar(line)
+			if k != "" {
+				inv.group(section).vars[k] = v
+			}
+		case "children":
+			child := strings.Fields(line)[0]
+			inv.group(child)
+			g := inv.group(section)
+			g.children = appendUnique(g.children, child)
+		case "hosts":
+			words := splitINIWords(line)
+			vars := map[string]string{}
+			for _, w := range words[1:] {
+				if k, v := splitINIVar(w); k != "" {
+					vars[k] = v
+				}
+			}
+			// host:port
+			pattern := words[0]
+			if i := strings.LastIndex(pattern, ":"); i > 0 && !strings.Contains(pattern[:i], ":") {
+				if _, err := strconv.Atoi(pattern[i+1:]); err == nil {
+					if _, ok := vars["ansible_port"]; !ok {
+						vars["ansible_port"] = pattern[i+1:]
+					}
+					pattern = pattern[:i]
+				}
+			}
+			for _, name := range expandHostPattern(pattern) {
+				inv.addHost(section, name, vars)
+			}
+		default:
+			return fmt.Errorf("unknown section type on line %d: %s", n, kind)
+		}
+	}
+	return scanner.Err()
+}
+
+// splitINIVar splits "key=value" into its components. Quotes around
+// value are removed.
+func splitINIVar(s string) (key, value string) {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return "", ""
+	}
+	key, value = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value
+}
+
+// splitINIWords splits a host line on whitespace, except within quotes.
+// Trailing comments are removed.
+func splitINIWords(s string) []string {
+	var (
+		words []string
+		word  []rune
+		quote rune
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			word = append(word, r)
+		case r == '"' || r == '\'':
+			quote = r
+			word = append(word, r)
+		case r == '#' && len(word) == 0:
+			return words
+		case r == ' ' || r == '\t':
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+		default:
+			word = append(word, r)
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// ansibleYAMLGroup is a group in a YAML inventory.
+type ansibleYAMLGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+	Vars     map[string]interface{}            `yaml:"vars"`
+	Children map[string]*ansibleYAMLGroup      `yaml:"children"`
+}
+
+// parseYAML parses an inventory in YAML (or JSON) format.
+func (inv *ansibleInventory) parseYAML(data []byte) error {
+	var groups map[string]*ansibleYAMLGroup
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(groups) {
+		inv.addYAMLGroup(name, groups[name])
+	}
+	return nil
+}
+
+// addYAMLGroup adds a group from a YAML inventory and its children.
+func (inv *ansibleInventory) addYAMLGroup(name string, yg *ansibleYAMLGroup) {
+	g := inv.group(name)
+	if yg == nil {
+		return
+	}
+	for k, v := range yg.Vars {
+		g.vars[k] = yamlString(v)
+	}
+
+	var names []string
+	for k := range yg.Hosts {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, pattern := range names {
+		vars := map[string]string{}
+		for k, v := range yg.Hosts[pattern] {
+			vars[k] = yamlString(v)
+		}
+		for _, host := range expandHostPattern(pattern) {
+			inv.addHost(name, host, vars)
+		}
+	}
+
+	for _, child := range sortedKeys(yg.Children) {
+		g.children = appendUnique(g.children, child)
+		inv.addYAMLGroup(child, yg.Children[child])
+	}
+}
+
+// sortedKeys returns the keys of a map of YAML groups in order.
+func sortedKeys(m map[string]*ansibleYAMLGroup) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// yamlString converts a scalar YAML value to a string.
+func yamlString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// loadVarsDirs reads variables from the group_vars and host_vars
+// directories in dir and returns the files and directories it read.
+func (inv *ansibleInventory) loadVarsDirs(dir string) []string {
+	var files []string
+	for _, kind := range []string{"group_vars", "host_vars"} {
+		vdir := filepath.Join(dir, kind)
+		infos, err := ioutil.ReadDir(vdir)
+		if err != nil {
+			continue
+		}
+		files = append(files, vdir)
+		for _, fi := range infos {
+			var (
+				name  = fi.Name()
+				path  = filepath.Join(vdir, name)
+				paths []string
+			)
+			if fi.IsDir() {
+				// group_vars/<group>/*.yml
+				files = append(files, path)
+				l, _ := filepath.Glob(filepath.Join(path, "*.y*ml"))
+				paths = append(paths, l...)
+			} else {
+				ext := filepath.Ext(name)
+				if ext != ".yml" && ext != ".yaml" {
+					continue
+				}
+				name = strings.TrimSuffix(name, ext)
+				paths = append(paths, path)
+			}
+
+			for _, p := range paths {
+				vars, err := readAnsibleVars(p)
+				if err != nil {
+					log.Printf("[ansible/%s] %v", p, err)
+					continue
+				}
+				files = append(files, p)
+				if kind == "group_vars" {
+					for k, v := range vars {
+						inv.group(name).vars[k] = v
+					}
+				} else if h, ok := inv.hosts[name]; ok {
+					// host_vars override variables set in the inventory
+					for k, v := range vars {
+						h.vars[k] = v
+					}
+				}
+			}
+		}
+	}
+	return files
+}
+
+// readAnsibleVars reads a YAML file of variables.
+func readAnsibleVars(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	for k, v := range m {
+		vars[k] = yamlString(v)
+	}
+	return vars, nil
+}
+
+// groupDepths returns the depth of each group, i.e. the length of the
+// longest chain of parents above it. Variables of deeper groups take
+// precedence.
+func (inv *ansibleInventory) groupDepths() map[string]int {
+	depths := map[string]int{}
+	var visit func(name string, depth int)
+	visit = func(name string, depth int) {
+		if d, ok := depths[name]; ok && d >= depth || depth > len(inv.groups) {
+			return
+		}
+		depths[name] = depth
+		for _, child := range inv.group(name).children {
+			visit(child, depth+1)
+		}
+	}
+	for name := range inv.groups {
+		visit(name, 0)
+	}
+	return depths
+}
+
+// hostGroups returns all the groups host belongs to, including the
+// parents of its groups, sorted by depth and name.
+func (inv *ansibleInventory) hostGroups(host string, depths map[string]int) []string {
+	parents := map[string][]string{}
+	for name, g := range inv.groups {
+		for _, child := range g.children {
+			parents[child] = append(parents[child], name)
+		}
+	}
+
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, p := range parents[name] {
+			visit(p)
+		}
+	}
+	for name, g := range inv.groups {
+		for _, h := range g.hosts {
+			if h == host {
+				visit(name)
+			}
+		}
+	}
+
+	var groups []string
+	for name := range seen {
+		groups = append(groups, name)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if depths[a] != depths[b] {
+			return depths[a] < depths[b]
+		}
+		return a < b
+	})
+	return groups
+}
+
+// baseHosts returns the inventory's hosts with their variables resolved.
+func (inv *ansibleInventory) baseHosts() []*BaseHost {
+	var (
+		hosts  []*BaseHost
+		depths = inv.groupDepths()
+	)
+
+	for _, name := range inv.order {
+		var (
+			groups = inv.hostGroups(name, depths)
+			vars   = map[string]string{}
+		)
+		// Precedence: all < parent groups < child groups < host
+		for k, v := range inv.group(ansibleAll).vars {
+			vars[k] = v
+		}
+		for _, g := range groups {
+			if g == ansibleAll {
+				continue
+			}
+			for k, v := range inv.groups[g].vars {
+				vars[k] = v
+			}
+		}
+		for k, v := range inv.hosts[name].vars {
+			vars[k] = v
+		}
+
+		h := &BaseHost{name: name, hostname: name}
+		if s := ansibleVar(vars, "ansible_host", "ansible_ssh_host"); s != "" {
+			h.hostname = s
+		}
+		if !IsValidHostname(h.hostname) {
+			log.Printf("[ansible] invalid hostname for %q: %q", name, h.hostname)
+			continue
+		}
+		if s := ansibleVar(vars, "ansible_port", "ansible_ssh_port"); s != "" {
+			if port, err := strconv.Atoi(s); err == nil {
+				h.port = port
+			} else {
+				log.Printf("[ansible] invalid port for %q: %q", name, s)
+			}
+		}
+		h.username = ansibleVar(vars, "ansible_user", "ansible_ssh_user")
+		h.meta.IdentityFile = ansibleVar(vars, "ansible_ssh_private_key_file")
+		if net.ParseIP(h.hostname) != nil {
+			h.meta.AddIP(h.hostname)
+		}
+		for _, g := range groups {
+			if g != ansibleAll && g != ansibleUngrouped {
+				h.meta.AddTag(g)
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
+
+// ansibleVar returns the value of the first of keys that is set. Values
+// containing Jinja2 templates are ignored, as they can't be evaluated.
+func ansibleVar(vars map[string]string, keys ...string) string {
+	for _, k := range keys {
+		v := vars[k]
+		if v == "" {
+			continue
+		}
+		if strings.Contains(v, "{{") || strings.Contains(v, "{%") {
+			log.Printf("[ansible] ignored template in %s: %s", k, v)
+			continue
+		}
+		return v
+	}
+	return ""
+}
+
+// expandHostPattern expands the ranges in an Ansible host pattern, e.g.
+// "web[01:03].example.com" or "db-[a:c]". Numeric ranges retain the
+// width of the start value, and may have a step, e.g. "[0:10:2]".
+func expandHostPattern(pattern string) []string {
+	i := strings.Index(pattern, "[")
+	j := strings.Index(pattern, "]")
+	if i < 0 || j < i {
+		return []string{pattern}
+	}
+	prefix, spec, suffix := pattern[:i], pattern[i+1:j], pattern[j+1:]
+
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return []string{pattern}
+	}
+	step := 1
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n < 1 {
+			return []string{pattern}
+		}
+		step = n
+	}
+
+	var values []string
+	if start, err := strconv.Atoi(parts[0]); err == nil {
+		end, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return []string{pattern}
+		}
+		format := "%d"
+		if len(parts[0]) > 1 && parts[0][0] == '0' {
+			format = fmt.Sprintf("%%0%dd", len(parts[0]))
+		}
+		for n := start; n <= end && len(values) < maxPatternHosts; n += step {
+			values = append(values, fmt.Sprintf(format, n))
+		}
+	} else if len(parts[0]) == 1 && len(parts[1]) == 1 {
+		for c := parts[0][0]; c <= parts[1][0]; c += byte(step) {
+			values = append(values, string(c))
+			if int(c)+step > 255 {
+				break
+			}
+		}
+	} else {
+		return []string{pattern}
+	}
+
+	var (
+		names []string
+		rests = expandHostPattern(suffix)
+	)
+	for _, v := range values {
+		for _, rest := range rests {
+			// Nested ranges multiply, so the total is limited, too
+			if len(names) == maxPatternHosts {
+				return names
+			}
+			names = append(names, prefix+v+rest)
+		}
+	}
+	return names
+}
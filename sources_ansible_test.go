@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var ansibleTests = map[string]string{
+	"hosts": `
+bastion ansible_host=192.168.1.1
+
+[webservers]
+web[1:2].example.com
+web3.example.com:2200  # comment
+
+[dbservers]
+db1 ansible_host=10.0.0.5 ansible_user="postgres"
+
+[dbservers:vars]
+ansible_port=2222
+
+[prod:children]
+webservers
+dbservers
+
+[prod:vars]
+ansible_user=deploy
+ansible_port=22
+
+[all:vars]
+ansible_user=admin
+`,
+	"hosts.yml": `
+all:
+  hosts:
+    bastion:
+      ansible_host: 192.168.1.1
+  vars:
+    ansible_user: admin
+  children:
+    prod:
+      vars:
+        ansible_user: deploy
+        ansible_port: 22
+      children:
+        webservers:
+          hosts:
+            web[1:2].example.com:
+            web3.example.com:
+              ansible_port: 2200
+        dbservers:
+          vars:
+            ansible_port: 2222
+          hosts:
+            db1:
+              ansible_host: 10.0.0.5
+              ansible_user: postgres
+`,
+}
+
+// TestAnsibleSource tests variable resolution in INI and YAML inventories.
+func TestAnsibleSource(t *testing.T) {
+	dir := testDir(t, ansibleTests)
+	// Sorted by name, as the formats order hosts differently
+	expected := []testHost{
+		{"bastion", "ssh://admin@192.168.1.1", nil, ""},
+		{"db1", "ssh://postgres@10.0.0.5:2222", []string{"prod", "dbservers"}, ""},
+		{"web1.example.com", "ssh://deploy@web1.example.com", []string{"prod", "webservers"}, ""},
+		{"web2.example.com", "ssh://deploy@web2.example.com", []string{"prod", "webservers"}, ""},
+		{"web3.example.com", "ssh://deploy@web3.example.com:2200", []string{"prod", "webservers"}, ""},
+	}
+	for name := range ansibleTests {
+		t.Run(name, func(t *testing.T) {
+			hosts := NewAnsibleSource(filepath.Join(dir, name), "ansible", 1).Hosts()
+			sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name() < hosts[j].Name() })
+			checkHosts(t, hosts, expected)
+		})
+	}
+}
+
+// TestAnsibleVarsDirs tests group_vars and host_vars directories.
+func TestAnsibleVarsDirs(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"inventory/hosts":                   "[web]\nweb1 ansible_host=10.9.9.9\nweb2\n",
+		"inventory/group_vars/web.yml":      "ansible_user: www\nansible_port: 2022\n",
+		"inventory/group_vars/all/main.yml": "ansible_user: root\n",
+		"inventory/host_vars/web1.yml":      "ansible_host: 10.1.1.1\n",
+		"inventory/host_vars/web2.yaml":     "ansible_host: 10.1.1.2\nansible_user: '{{ lookup_user }}'\n",
+	})
+
+	// host_vars override the inventory, and templates are ignored
+	checkHosts(t, NewAnsibleSource(filepath.Join(dir, "inventory"), "ansible", 1).Hosts(), []testHost{
+		{"web1", "ssh://www@10.1.1.1:2022", []string{"web"}, ""},
+		{"web2", "ssh://10.1.1.2:2022", []string{"web"}, ""},
+	})
+}
+
+func TestExpandHostPattern(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"web.example.com", []string{"web.example.com"}},
+		{"web[1:3]", []string{"web1", "web2", "web3"}},
+		{"web[01:03:2].lan", []string{"web01.lan", "web03.lan"}},
+		{"db-[a:c]", []string{"db-a", "db-b", "db-c"}},
+		{"r[1:2]n[1:2]", []string{"r1n1", "r1n2", "r2n1", "r2n2"}},
+		{"bad[x:10]", []string{"bad[x:10]"}},
+	}
+	for _, td := range tests {
+		v := expandHostPattern(td.in)
+		if !reflect.DeepEqual(v, td.out) {
+			t.Errorf("Bad Pattern for %q. Expected=%v, Got=%v", td.in, td.out, v)
+		}
+	}
+
+	// Nested ranges are limited in total
+	if n := len(expandHostPattern("h[0:999]-[0:999]")); n != maxPatternHosts {
+		t.Errorf("Bad nested range. Expected=%d hosts, Got=%d", maxPatternHosts, n)
+	}
+}
@@ -0,0 +1,220 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version of cache file format. Increment when it changes.
+const hostCacheVersion = 2
+
+// HostCache stores the Hosts parsed from files, so the files needn't be
+// parsed again until they change. Cached Hosts are invalidated when the
+// size or modification time of any file they were read from changes.
+type HostCache struct {
+	Dir string // Directory cache files are stored in
+}
+
+// NewHostCache creates a new HostCache that stores its data in dir.
+func NewHostCache(dir string) *HostCache { return &HostCache{Dir: dir} }
+
+// fileStat is the state of a file when Hosts were read from it.
+type fileStat struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// Types of cachedHost.
+const (
+	cachedBaseHost          = ""
+	cachedConfigHost        = "config"
+	cachedDockerMachineHost = "docker-machine"
+)
+
+// cachedHost is the serialised form of a BaseHost, ConfigHost or
+// DockerMachineHost.
+type cachedHost struct {
+	Type     string `json:"type,omitempty"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Username string `json:"user,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Meta     Meta   `json:"meta"`
+}
+
+// hostCacheEntry is the data stored for a file.
+type hostCacheEntry struct {
+	Version int          `json:"version"`
+	Files   []fileStat   `json:"files"`
+	Hosts   []cachedHost `json:"hosts"`
+}
+
+// Load returns the Hosts cached for file path of the given kind (e.g.
+// "config"). ok is false if there is no cached data or it is stale.
+func (c *HostCache) Load(kind, path string) (hosts []Host, ok bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(c.cachePath(kind, path))
+	if err != nil {
+		return nil, false
+	}
+	var e hostCacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		log.Printf("[cache/%s] invalid data: %v", kind, err)
+		return nil, false
+	}
+	if e.Version != hostCacheVersion || len(e.Files) == 0 || e.Files[0].Path != path {
+		return nil, false
+	}
+	for _, fs := range e.Files {
+		if !fs.current() {
+			log.Printf("[cache/%s] %s has changed", kind, fs.Path)
+			return nil, false
+		}
+	}
+
+	hosts = make([]Host, len(e.Hosts))
+	for i, ch := range e.Hosts {
+		b := BaseHost{
+			name:     ch.Name,
+			hostname: ch.Hostname,
+			username: ch.Username,
+			port:     ch.Port,
+			meta:     ch.Meta,
+		}
+		switch ch.Type {
+		case cachedConfigHost:
+			hosts[i] = &ConfigHost{BaseHost: b}
+		case cachedDockerMachineHost:
+			hosts[i] = &DockerMachineHost{b}
+		default:
+			hosts[i] = &b
+		}
+	}
+	return hosts, true
+}
+
+// Store caches the Hosts parsed from path and any other files they were
+// read from, e.g. Included files. Directories may also be passed in
+// files, in which case adding or removing files invalidates the cache.
+//
+// Only BaseHost, ConfigHost and DockerMachineHost can be cached. If
+// hosts contains any other type, nothing is stored and an error is
+// returned.
+func (c *HostCache) Store(kind, path string, hosts []Host, files ...string) error {
+	if c == nil {
+		return nil
+	}
+
+	e := hostCacheEntry{Version: hostCacheVersion, Hosts: make([]cachedHost, len(hosts))}
+	for _, p := range append([]string{path}, files...) {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		e.Files = append(e.Files, fileStat{p, fi.Size(), fi.ModTime()})
+	}
+
+	for i, h := range hosts {
+		var (
+			b   *BaseHost
+			typ string
+		)
+		switch v := h.(type) {
+		case *BaseHost:
+			b, typ = v, cachedBaseHost
+		case *ConfigHost:
+			b, typ = &v.BaseHost, cachedConfigHost
+		case *DockerMachineHost:
+			b, typ = &v.BaseHost, cachedDockerMachineHost
+		default:
+			return fmt.Errorf("can't cache host %q of type %T", h.Name(), h)
+		}
+		e.Hosts[i] = cachedHost{typ, b.name, b.hostname, b.username, b.port, b.meta}
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.cachePath(kind, path), data)
+}
+
+// LoadData returns the raw data, e.g. a command's output or an API
+// response, cached under key by StoreData. fresh is false if the data
+// are older than maxAge (0 = never fresh). Stale data are still returned,
+// so sources can fall back to them if they can't fetch new data.
+func (c *HostCache) LoadData(kind, key string, maxAge time.Duration) (data []byte, fresh bool) {
+	if c == nil {
+		return nil, false
+	}
+	p := c.dataPath(kind, key)
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, false
+	}
+	if data, err = ioutil.ReadFile(p); err != nil {
+		return nil, false
+	}
+	return data, time.Since(fi.ModTime()) < maxAge
+}
+
+// StoreData caches raw data under key.
+func (c *HostCache) StoreData(kind, key string, data []byte) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.dataPath(kind, key), data)
+}
+
+// cachePath returns the path of the cache file for path.
+func (c *HostCache) cachePath(kind, path string) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("%s-%x.json", kind, sha1.Sum([]byte(path))))
+}
+
+// dataPath returns the path of the raw data file for key.
+func (c *HostCache) dataPath(kind, key string) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("%s-%x.data", kind, sha1.Sum([]byte(key))))
+}
+
+// writeFileAtomic writes data to a temporary file first and then renames
+// it, so concurrent readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := fmt.Sprintf("%s.%d", path, os.Getpid())
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// current returns true if the file hasn't changed.
+func (fs fileStat) current() bool {
+	fi, err := os.Stat(fs.Path)
+	if err != nil {
+		return false
+	}
+	return fi.Size() == fs.Size && fi.ModTime().Equal(fs.ModTime)
+}
@@ -0,0 +1,181 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Defaults for CommandSource.
+const (
+	DefaultCommandTimeout = 10 * time.Second
+	DefaultCommandMaxAge  = 5 * time.Minute
+)
+
+// commandHost is a host in the output of a CommandSource command.
+//
+//	[
+//	  {
+//	    "name": "web1",              // optional, defaults to hostname
+//	    "hostname": "10.0.0.7",      // required
+//	    "port": 2222,                // optional, defaults to 22
+//	    "user": "deploy",            // optional
+//	    "tags": ["prod", "web"]      // optional
+//	  }
+//	]
+type commandHost struct {
+	Name     string   `json:"name"`
+	Hostname string   `json:"hostname"`
+	Port     int      `json:"port"`
+	User     string   `json:"user"`
+	Tags     []string `json:"tags"`
+}
+
+// CommandSource implements Source for an external program that prints
+// a JSON array of hosts (see commandHost for the format) to STDOUT.
+// Anything the program writes to STDERR is logged.
+//
+// The program's output is cached for MaxAge. If the program fails or
+// times out, stale cached output is used instead.
+type CommandSource struct {
+	baseSource
+	Command string        // Command line, run with /bin/sh
+	MaxAge  time.Duration // How long to cache output
+	timeout time.Duration
+}
+
+// NewCommandSource creates a new CommandSource for a shell command line.
+func NewCommandSource(command, name string, priority int, timeout time.Duration) *CommandSource {
+	s := &CommandSource{Command: command, MaxAge: DefaultCommandMaxAge, timeout: timeout}
+	s.name = name
+	s.priority = priority
+	if s.timeout == 0 {
+		s.timeout = DefaultCommandTimeout
+	}
+	return s
+}
+
+// Timeout implements TimeoutSource.
+func (s *CommandSource) Timeout() time.Duration { return s.timeout }
+
+// Hosts implements Source.
+func (s *CommandSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.HostsContext(context.Background())
+	}
+	return s.hosts
+}
+
+// HostsContext implements ContextSource.
+func (s *CommandSource) HostsContext(ctx context.Context) ([]Host, error) {
+	if s.hosts != nil {
+		return s.hosts, nil
+	}
+
+	data, err := s.loadCachedData("command", s.Command, s.MaxAge, func() ([]byte, error) {
+		return s.run(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := parseCommandOutput(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output of %q: %v", s.Command, err)
+	}
+	s.hosts = make([]Host, len(hosts))
+	for i, h := range hosts {
+		h.source = s.Name()
+		s.hosts[i] = h
+	}
+	log.Printf("[source/load/command] %d host(s) from '%s'", len(s.hosts), s.Name())
+	return s.hosts, nil
+}
+
+// run executes the command and returns its output.
+func (s *CommandSource) run(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("/bin/sh", "-c", s.Command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Run in own process group, so any children of the shell can be
+	// killed, too.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		err = <-done
+	}
+
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		log.Printf("[source/command/%s] stderr: %s", s.Name(), scanner.Text())
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("command %q failed: %v", s.Command, err)
+	}
+	log.Printf("[source/command/%s] ran in %v", s.Name(), time.Since(start))
+	return stdout.Bytes(), nil
+}
+
+// parseCommandOutput parses the JSON output of a CommandSource.
+func parseCommandOutput(data []byte) ([]*BaseHost, error) {
+	var (
+		entries []commandHost
+		hosts   []*BaseHost
+	)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	for i, e := range entries {
+		e.Hostname = strings.TrimSpace(e.Hostname)
+		if !IsValidHostname(e.Hostname) {
+			log.Printf("[source/command] invalid hostname for entry #%d: %q", i+1, e.Hostname)
+			continue
+		}
+		if e.Name == "" {
+			e.Name = e.Hostname
+		}
+		h := &BaseHost{name: e.Name, hostname: e.Hostname, username: e.User, port: e.Port}
+		h.meta.AddTag(e.Tags...)
+		if net.ParseIP(e.Hostname) != nil {
+			h.meta.AddIP(e.Hostname)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const testCommandOutput = `[
+	{"name": "web1", "hostname": "10.0.0.7", "port": 2222, "user": "deploy", "tags": ["prod", "web"]},
+	{"hostname": "db.example.com"},
+	{"name": "broken", "hostname": "not a hostname"}
+]`
+
+// TestCommandSource tests hosts from an external command.
+func TestCommandSource(t *testing.T) {
+	cmd := "echo 'warning: something' >&2; cat <<'EOF'\n" + testCommandOutput + "\nEOF"
+	s := NewCommandSource(cmd, "cmdb", 1, time.Second)
+	s.Cache = NewHostCache(testDir(t, nil))
+	hosts, err := s.HostsContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHosts(t, hosts, []testHost{
+		{"web1", "ssh://deploy@10.0.0.7:2222", []string{"prod", "web"}, ""},
+		{"db.example.com", "ssh://db.example.com", nil, ""},
+	})
+
+	// Output is cached
+	if _, fresh := s.Cache.LoadData("command", cmd, time.Minute); !fresh {
+		t.Error("Output not cached")
+	}
+
+	// Timeout with stale cache falls back to cached output
+	s2 := NewCommandSource(cmd, "cmdb", 1, 50*time.Millisecond)
+	s2.Cache = s.Cache
+	s2.MaxAge = 0
+	s2.Command = "sleep 5"
+	s2.Cache.StoreData("command", s2.Command, []byte(testCommandOutput))
+	start := time.Now()
+	if hosts, err := s2.HostsContext(context.Background()); err != nil || len(hosts) != 2 {
+		t.Errorf("Expected 2 stale hosts, Got=%v (err=%v)", hosts, err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Command not killed after timeout: %v", d)
+	}
+
+	// Timeout without cache is an error
+	s3 := NewCommandSource("sleep 5", "cmdb", 1, 50*time.Millisecond)
+	if _, err := s3.HostsContext(context.Background()); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, Got=%v", err)
+	}
+
+	// Invalid output
+	s4 := NewCommandSource("echo nope", "cmdb", 1, time.Second)
+	if _, err := s4.HostsContext(context.Background()); err == nil {
+		t.Error("Accepted invalid output")
+	}
+}
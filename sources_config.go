@@ -9,10 +9,11 @@
 package ssh
 
 import (
-	"fmt"
 	"log"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -46,10 +47,10 @@ func (h *ConfigHost) SetUsername(n string) {
 func (h *ConfigHost) SSHURL() *url.URL {
 	u := &url.URL{
 		Scheme: "ssh",
-		Host:   h.Name(),
+		Host:   urlHost(h.name, 22),
 	}
 	if h.forcePort {
-		u.Host = fmt.Sprintf("%s:%d", u.Host, h.Port())
+		u.Host = net.JoinHostPort(stripBrackets(h.name), strconv.Itoa(h.Port()))
 	}
 	if h.forceUsername {
 		u.User = url.User(h.Username())
@@ -58,19 +59,19 @@ func (h *ConfigHost) SSHURL() *url.URL {
 }
 
 // MoshCmd implements Host.
-func (h *ConfigHost) MoshCmd(path string) string {
+func (h *ConfigHost) MoshCmd(path string) *Command {
 	if path == "" {
 		path = "mosh"
 	}
-	cmd := path + " "
+	cmd := NewCommand(path)
 	if h.forcePort {
-		cmd += fmt.Sprintf("--ssh 'ssh -p %d' ", h.Port())
+		cmd.Add("--ssh", NewCommand("ssh", "-p", strconv.Itoa(h.Port())).String())
 	}
-	if h.forceUsername && h.Username() != "" {
-		cmd += h.Username() + "@"
+	user := ""
+	if h.forceUsername {
+		user = h.Username()
 	}
-	cmd += h.Name()
-	return cmd
+	return cmd.AddTarget(userHost(user, h.name))
 }
 
 // ConfigSource implements Source for ssh config-formatted files.
@@ -90,37 +91,75 @@ func NewConfigSource(path, name string, priority int) *ConfigSource {
 // Hosts implements Source.
 func (s *ConfigSource) Hosts() []Host {
 	if s.hosts == nil {
-		hosts := parseConfigFile(s.Filepath)
-		log.Printf("[source/load/config] %d host(s) in '%s'", len(hosts), s.Name())
-		s.hosts = make([]Host, len(hosts))
-		for i, h := range hosts {
-			h.source = s.Name()
-			s.hosts[i] = Host(h)
-		}
+		s.hosts = s.loadHosts("config", func() ([]Host, []string) {
+			hosts, files := parseConfigFile(s.Filepath)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, files
+		})
 	}
 	return s.hosts
 }
 
-// parseConfigFile parse an SSH config file.
-func parseConfigFile(path string) []*ConfigHost {
-	var hosts []*ConfigHost
+// Maximum depth of nested Include directives (same as ssh).
+const maxIncludeDepth = 16
+
+// parseConfigFile parses an SSH config file and the files it Includes.
+// It also returns the paths of the Included files and of the directories
+// searched for them.
+func parseConfigFile(path string) ([]*ConfigHost, []string) {
+	return parseConfig(path, filepath.Dir(path), 0)
+}
+
+// parseConfig parses an SSH config file. Relative Include paths are
+// resolved against dir.
+func parseConfig(path, dir string, depth int) (hosts []*ConfigHost, files []string) {
 	r, err := os.Open(path)
 	if err != nil {
 		log.Printf("[config/%s] Error opening file: %s", path, err)
-		return hosts
+		return
 	}
+	defer r.Close()
 	cfg, err := ssh_config.Parse(r)
 	if err != nil {
 		log.Printf("[config/%s] Parse error: %s", path, err)
-		return hosts
+		return
 	}
 
+	// Include directives are followed where they appear: global ones
+	// before any hosts and others after the hosts of their Host block.
+	include := func(params []*ssh_config.Param) {
+		for _, p := range params {
+			if !strings.EqualFold(p.Keyword, "Include") {
+				continue
+			}
+			if depth >= maxIncludeDepth {
+				log.Printf("[config/%s] Include nested too deeply", path)
+				return
+			}
+			for _, pat := range p.Args {
+				paths, searched := includePaths(pat, dir)
+				files = append(files, searched...)
+				for _, inc := range paths {
+					h, f := parseConfig(inc, dir, depth+1)
+					hosts = append(hosts, h...)
+					files = append(files, inc)
+					files = append(files, f...)
+				}
+			}
+		}
+	}
+	include(cfg.Globals)
+
 	for _, e := range cfg.Hosts {
 		var (
 			p    *ssh_config.Param
 			port = 22
 			hn   string // hostname
 			user string
+			meta Meta
 		)
 
 		p = e.GetParam(ssh_config.HostKeyword)
@@ -151,6 +190,25 @@ func parseConfigFile(path string) []*ConfigHost {
 			user = p.Value()
 		}
 
+		p = e.GetParam(ssh_config.IdentityFileKeyword)
+		if p != nil {
+			meta.IdentityFile = p.Value()
+		}
+
+		p = e.GetParam("ProxyJump")
+		if p != nil && p.Value() != "none" {
+			meta.JumpHost = p.Value()
+		}
+
+		// Use comments above Host as description
+		var comments []string
+		for _, s := range e.Comments {
+			if s = strings.TrimSpace(strings.TrimLeft(s, "#")); s != "" {
+				comments = append(comments, s)
+			}
+		}
+		meta.Description = strings.Join(comments, " ")
+
 		for _, n := range e.Hostnames {
 			if strings.Contains(n, "*") || strings.Contains(n, "!") || strings.Contains(n, "?") {
 				continue
@@ -165,9 +223,36 @@ func parseConfigFile(path string) []*ConfigHost {
 			if hn != "" {
 				h.hostname = hn
 			}
+			h.meta = meta
+			if net.ParseIP(h.hostname) != nil {
+				h.meta.AddIP(h.hostname)
+			}
 			// log.Printf("%+v", host)
 			hosts = append(hosts, h)
 		}
+		include(e.Params)
+	}
+	return
+}
+
+// includePaths returns the files matched by the pattern of an Include
+// directive and the directories that were searched.
+func includePaths(pattern, dir string) (paths, searched []string) {
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		pattern = os.Getenv("HOME") + pattern[1:]
+	} else if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		log.Printf("[config] Invalid Include pattern %q: %v", pattern, err)
+		return nil, nil
+	}
+	// Adding or removing a file changes the modification time of its
+	// directory, so caches notice new matches.
+	if len(paths) == 0 || strings.ContainsAny(pattern, "*?[") {
+		searched = append(searched, filepath.Dir(pattern))
 	}
-	return hosts
+	return paths, searched
 }
@@ -0,0 +1,245 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for ConsulSource.
+const (
+	DefaultConsulAddress = "http://127.0.0.1:8500"
+	DefaultConsulTimeout = 10 * time.Second
+	DefaultConsulMaxAge  = 5 * time.Minute
+)
+
+// How many services ConsulSource looks up at once. The catalog API has
+// no call that returns the services of all nodes.
+const consulWorkers = 8
+
+// consulNode is a node in Consul's catalog with the names of the services
+// registered on it. It's also the format in which nodes are cached.
+type consulNode struct {
+	Node       string   `json:"node"`
+	Address    string   `json:"address"`
+	Datacenter string   `json:"datacenter"`
+	Services   []string `json:"services"`
+}
+
+// ConsulSource implements Source for the nodes in the catalog of a Consul
+// agent. Nodes are tagged with their datacentre and the names of the
+// services registered on them, so searching for a service shows the
+// nodes it runs on.
+//
+// The nodes are cached for MaxAge.
+type ConsulSource struct {
+	baseSource
+	Address     string        // URL of Consul agent's HTTP API
+	Token       string        // ACL token (optional)
+	Datacenters []string      // Datacentres to list; default is agent's
+	MaxAge      time.Duration // How long to cache nodes
+	Client      *http.Client
+	timeout     time.Duration
+}
+
+// NewConsulSource creates a new ConsulSource for the agent at address.
+func NewConsulSource(address, name string, priority int, timeout time.Duration) *ConsulSource {
+	if address == "" {
+		address = DefaultConsulAddress
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	s := &ConsulSource{
+		Address: strings.TrimSuffix(address, "/"),
+		MaxAge:  DefaultConsulMaxAge,
+		Client:  http.DefaultClient,
+		timeout: timeout,
+	}
+	s.name = name
+	s.priority = priority
+	if s.timeout == 0 {
+		s.timeout = DefaultConsulTimeout
+	}
+	return s
+}
+
+// Timeout implements TimeoutSource.
+func (s *ConsulSource) Timeout() time.Duration { return s.timeout }
+
+// Hosts implements Source.
+func (s *ConsulSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.HostsContext(context.Background())
+	}
+	return s.hosts
+}
+
+// HostsContext implements ContextSource.
+func (s *ConsulSource) HostsContext(ctx context.Context) ([]Host, error) {
+	if s.hosts != nil {
+		return s.hosts, nil
+	}
+
+	key := s.Address + "?dc=" + strings.Join(s.Datacenters, ",")
+	data, err := s.loadCachedData("consul", key, s.MaxAge, func() ([]byte, error) {
+		nodes, err := s.fetchNodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(nodes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []consulNode
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	s.hosts = []Host{}
+	for _, n := range nodes {
+		if !IsValidHostname(n.Address) {
+			log.Printf("[source/consul/%s] invalid address for %q: %q", s.Name(), n.Node, n.Address)
+			continue
+		}
+		h := &BaseHost{name: n.Node, hostname: n.Address, source: s.Name()}
+		h.meta.AddTag("consul", n.Datacenter)
+		h.meta.AddTag(n.Services...)
+		h.meta.Set("datacenter", n.Datacenter)
+		if net.ParseIP(n.Address) != nil {
+			h.meta.AddIP(n.Address)
+		}
+		s.hosts = append(s.hosts, h)
+	}
+	log.Printf("[source/load/consul] %d host(s) from '%s'", len(s.hosts), s.Name())
+	return s.hosts, nil
+}
+
+// fetchNodes retrieves the nodes and their services from the catalog of
+// each datacentre.
+func (s *ConsulSource) fetchNodes(ctx context.Context) ([]consulNode, error) {
+	dcs := s.Datacenters
+	if len(dcs) == 0 {
+		dcs = []string{""} // agent's datacentre
+	}
+
+	var (
+		nodes []consulNode
+		start = time.Now()
+	)
+	for _, dc := range dcs {
+		var catalog []struct {
+			Node       string
+			Address    string
+			Datacenter string
+		}
+		if err := s.get(ctx, "/v1/catalog/nodes", dc, &catalog); err != nil {
+			return nil, err
+		}
+
+		var services map[string][]string // name -> tags
+		if err := s.get(ctx, "/v1/catalog/services", dc, &services); err != nil {
+			return nil, err
+		}
+		var names []string
+		for name := range services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		byNode, err := s.serviceNodes(ctx, dc, names)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range catalog {
+			nodes = append(nodes, consulNode{n.Node, n.Address, n.Datacenter, byNode[n.Node]})
+		}
+	}
+	log.Printf("[source/consul/%s] fetched %d node(s) in %v", s.Name(), len(nodes), time.Since(start))
+	return nodes, nil
+}
+
+// serviceNodes returns the names of the services in datacentre dc by
+// node. The services are looked up in parallel.
+func (s *ConsulSource) serviceNodes(ctx context.Context, dc string, names []string) (map[string][]string, error) {
+	var (
+		instances = make([][]struct{ Node string }, len(names))
+		errs      = make([]error, len(names))
+		jobs      = make(chan int)
+		wg        sync.WaitGroup
+	)
+	for n := 0; n < consulWorkers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = s.get(ctx, "/v1/catalog/service/"+url.PathEscape(names[i]), dc, &instances[i])
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	byNode := map[string][]string{}
+	for i, name := range names {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, inst := range instances[i] {
+			byNode[inst.Node] = appendUnique(byNode[inst.Node], name)
+		}
+	}
+	return byNode, nil
+}
+
+// get fetches path from the Consul API and unmarshals the JSON response
+// into v. dc is the datacentre to query.
+func (s *ConsulSource) get(ctx context.Context, path, dc string, v interface{}) error {
+	u := s.Address + path
+	if dc != "" {
+		u += "?dc=" + url.QueryEscape(dc)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	if s.Token != "" {
+		req.Header.Set("X-Consul-Token", s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Consul API: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,80 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testConsulAPI is the responses of a fake Consul agent by datacentre and path.
+var testConsulAPI = map[string]map[string]string{
+	"dc1": {
+		"/v1/catalog/nodes": `[
+			{"ID": "40e4a748", "Node": "web1", "Address": "10.1.10.12", "Datacenter": "dc1"},
+			{"ID": "6e6a3ea4", "Node": "db1", "Address": "10.1.10.20", "Datacenter": "dc1"}
+		]`,
+		"/v1/catalog/services":      `{"consul": [], "nginx": ["http"], "postgres": ["primary"]}`,
+		"/v1/catalog/service/nginx": `[{"Node": "web1", "ServiceName": "nginx", "ServicePort": 80}]`,
+		"/v1/catalog/service/postgres": `[
+			{"Node": "db1", "ServiceName": "postgres", "ServicePort": 5432}
+		]`,
+		"/v1/catalog/service/consul": `[{"Node": "web1"}, {"Node": "db1"}]`,
+	},
+	"dc2": {
+		"/v1/catalog/nodes":          `[{"Node": "web2", "Address": "web2.dc2.example.com", "Datacenter": "dc2"}]`,
+		"/v1/catalog/services":       `{"nginx": []}`,
+		"/v1/catalog/service/nginx":  `[{"Node": "web2"}]`,
+		"/v1/catalog/service/consul": `[]`,
+	},
+}
+
+// TestConsulSource tests loading nodes from a fake Consul agent.
+func TestConsulSource(t *testing.T) {
+	ts := testServer(t, "X-Consul-Token", "s3cret", "ACL not found", func(w http.ResponseWriter, r *http.Request) {
+		dc := r.URL.Query().Get("dc")
+		if dc == "" {
+			dc = "dc1"
+		}
+		data, ok := testConsulAPI[dc][r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, data)
+	})
+
+	s := NewConsulSource(ts.URL, "consul", 1, time.Second)
+	s.Token = "s3cret"
+	s.Datacenters = []string{"dc1", "dc2"}
+
+	hosts, err := s.HostsContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nodes are tagged with their datacentres and services
+	checkHosts(t, hosts, []testHost{
+		{"web1", "ssh://10.1.10.12", []string{"consul", "dc1", "nginx"}, ""},
+		{"db1", "ssh://10.1.10.20", []string{"consul", "dc1", "postgres"}, ""},
+		{"web2", "ssh://web2.dc2.example.com", []string{"consul", "dc2", "nginx"}, ""},
+	})
+	if x := hosts[2].Meta().Get("datacenter"); x != "dc2" {
+		t.Errorf("Bad datacenter. Expected=dc2, Got=%q", x)
+	}
+
+	// API errors
+	s2 := NewConsulSource(ts.URL, "consul", 1, time.Second)
+	_, err = s2.HostsContext(context.Background())
+	if x := "Consul API: 403 Forbidden: ACL not found"; err == nil || err.Error() != x {
+		t.Errorf("Bad error. Expected=%q, Got=%v", x, err)
+	}
+}
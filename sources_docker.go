@@ -0,0 +1,213 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dockerMachine is the config.json of a Docker Machine. Only the fields
+// common to most drivers are read.
+type dockerMachine struct {
+	Name       string
+	DriverName string
+	Driver     struct {
+		IPAddress  string
+		SSHUser    string
+		SSHPort    int
+		SSHKeyPath string
+	}
+}
+
+// dockerContext is the meta.json of a Docker context.
+type dockerContext struct {
+	Name     string
+	Metadata struct {
+		Description string
+	}
+	Endpoints struct {
+		Docker struct {
+			Host string
+		} `json:"docker"`
+	}
+}
+
+// DockerSource implements Source for the remote Docker hosts in Docker's
+// config directory (~/.docker): machines created with docker-machine and
+// contexts whose endpoint is an ssh:// URL. Machines are connected to
+// with their SSH user, port and private key.
+type DockerSource struct {
+	baseSource
+}
+
+// NewDockerSource creates a new DockerSource for a Docker config directory.
+func NewDockerSource(path, name string, priority int) *DockerSource {
+	s := &DockerSource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *DockerSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("docker", func() ([]Host, []string) {
+			machines, files := readDockerMachines(filepath.Join(s.Filepath, "machine/machines"))
+			contexts, files2 := readDockerContexts(filepath.Join(s.Filepath, "contexts/meta"))
+			l := []Host{}
+			for _, h := range machines {
+				l = append(l, h)
+			}
+			for _, h := range contexts {
+				l = append(l, h)
+			}
+			return l, append(files, files2...)
+		})
+	}
+	return s.hosts
+}
+
+// DockerMachineHost is a machine created by docker-machine. Like
+// VagrantHost, its ssh and mosh commands include its private key
+// (Meta.IdentityFile), as machines only accept the key docker-machine
+// generated for them.
+type DockerMachineHost struct {
+	BaseHost
+}
+
+// SSHCmd implements Host.
+func (h *DockerMachineHost) SSHCmd(path string) *Command {
+	return sshCmd(h, path, h.meta.IdentityFile)
+}
+
+// MoshCmd implements Host.
+func (h *DockerMachineHost) MoshCmd(path string) *Command {
+	return moshCmd(h, path, h.meta.IdentityFile)
+}
+
+// readDockerMachines reads the config.json of each machine in dir. It
+// also returns the paths of the directory and the files.
+func readDockerMachines(dir string) ([]*DockerMachineHost, []string) {
+	paths, files := dockerConfigFiles(dir, "config.json")
+
+	var hosts []*DockerMachineHost
+	for _, path := range paths {
+		var m dockerMachine
+		if err := readDockerJSON(path, &m); err != nil {
+			log.Printf("[docker/%s] %v", path, err)
+			continue
+		}
+		if m.Name == "" {
+			m.Name = filepath.Base(filepath.Dir(path))
+		}
+		d := m.Driver
+		host := d.IPAddress
+		// VirtualBox machines are reached via a port forwarded to localhost
+		if m.DriverName == "virtualbox" {
+			host = "127.0.0.1"
+		}
+		if host == "" {
+			log.Printf("[docker/%s] no address for machine %q", path, m.Name)
+			continue
+		}
+		if !IsValidHostname(host) {
+			log.Printf("[docker/%s] invalid hostname for %q: %q", path, m.Name, host)
+			continue
+		}
+		h := &DockerMachineHost{BaseHost{name: m.Name, hostname: host, username: d.SSHUser, port: d.SSHPort}}
+		h.meta.AddTag("docker-machine", m.DriverName)
+		h.meta.IdentityFile = d.SSHKeyPath
+		if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() {
+			h.meta.AddIP(host)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, files
+}
+
+// readDockerContexts reads the meta.json of each context in dir. Only
+// contexts with an ssh:// endpoint are returned. It also returns the
+// paths of the directory and the files.
+func readDockerContexts(dir string) ([]*BaseHost, []string) {
+	paths, files := dockerConfigFiles(dir, "meta.json")
+
+	var hosts []*BaseHost
+	for _, path := range paths {
+		var c dockerContext
+		if err := readDockerJSON(path, &c); err != nil {
+			log.Printf("[docker/%s] %v", path, err)
+			continue
+		}
+		u, err := url.Parse(c.Endpoints.Docker.Host)
+		if err != nil || u.Scheme != "ssh" || u.Hostname() == "" {
+			continue
+		}
+		host := u.Hostname()
+		if !IsValidHostname(host) {
+			log.Printf("[docker/%s] invalid hostname for %q: %q", path, c.Name, host)
+			continue
+		}
+		h := &BaseHost{name: c.Name, hostname: host}
+		if u.User != nil {
+			h.username = u.User.Username()
+		}
+		if p, err := strconv.Atoi(u.Port()); err == nil {
+			h.port = p
+		}
+		h.meta.AddTag("docker-context")
+		h.meta.Description = strings.TrimSpace(c.Metadata.Description)
+		if net.ParseIP(host) != nil {
+			h.meta.AddIP(host)
+		}
+		hosts = append(hosts, h)
+	}
+	sort.SliceStable(hosts, func(i, j int) bool { return hosts[i].name < hosts[j].name })
+	return hosts, files
+}
+
+// dockerConfigFiles returns the paths of the files called name in the
+// subdirectories of dir, and the paths of dir and the files for caching.
+func dockerConfigFiles(dir, name string) (paths, files []string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+	files = append(files, dir)
+	for _, fi := range infos {
+		if !fi.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name(), name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		paths = append(paths, path)
+		files = append(files, path)
+	}
+	return paths, files
+}
+
+// readDockerJSON unmarshals the JSON file at path into v.
+func readDockerJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,55 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestDockerSource tests reading Docker Machines and contexts.
+func TestDockerSource(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"machine/machines/dev/config.json": `{"ConfigVersion": 3, "Name": "dev", "DriverName": "virtualbox",
+			"Driver": {"IPAddress": "192.168.99.100", "SSHUser": "docker", "SSHPort": 50122,
+			"SSHKeyPath": "/Users/bob/.docker/machine/machines/dev/id_rsa"}}`,
+		"machine/machines/prod/config.json": `{"Name": "prod", "DriverName": "generic",
+			"Driver": {"IPAddress": "203.0.113.7", "SSHUser": "ubuntu", "SSHPort": 22,
+			"SSHKeyPath": "/Users/bob/.ssh/prod"}}`,
+		"machine/machines/broken/config.json": `{"Name": "broken", "DriverName": "none", "Driver": {}}`,
+		"contexts/meta/4f1c/meta.json": `{"Name": "swarm", "Metadata": {"Description": "Swarm manager"},
+			"Endpoints": {"docker": {"Host": "ssh://deploy@swarm.example.com:2200", "SkipTLSVerify": false}}}`,
+		"contexts/meta/9a0e/meta.json": `{"Name": "tcp", "Metadata": {},
+			"Endpoints": {"docker": {"Host": "tcp://10.0.0.3:2376"}}}`,
+	})
+
+	// VirtualBox machines are reached via a forwarded port on localhost,
+	// and only contexts with ssh:// endpoints are included
+	checkHosts(t, NewDockerSource(dir, "Docker", 1).Hosts(), []testHost{
+		{"dev", "ssh://docker@127.0.0.1:50122", []string{"docker-machine", "virtualbox"}, ""},
+		{"prod", "ssh://ubuntu@203.0.113.7", []string{"docker-machine", "generic"}, ""},
+		{"swarm", "ssh://deploy@swarm.example.com:2200", []string{"docker-context"}, "Swarm manager"},
+	})
+
+	// Machines' keys are passed to ssh and mosh, also after caching
+	cache := NewHostCache(filepath.Join(dir, "cache"))
+	for i := 0; i < 2; i++ {
+		s := NewDockerSource(dir, "Docker", 1)
+		s.Cache = cache
+		h := s.Hosts()[0]
+		x := "ssh -p 50122 -i /Users/bob/.docker/machine/machines/dev/id_rsa docker@127.0.0.1"
+		if v := h.SSHCmd("").String(); v != x {
+			t.Errorf("[%d] Bad ssh command. Expected=%q, Got=%q", i, x, v)
+		}
+		x = "mosh --ssh 'ssh -p 50122 -i /Users/bob/.docker/machine/machines/dev/id_rsa' docker@127.0.0.1"
+		if v := h.MoshCmd("").String(); v != x {
+			t.Errorf("[%d] Bad mosh command. Expected=%q, Got=%q", i, x, v)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults for EC2Source.
+const (
+	DefaultEC2Timeout = 20 * time.Second
+	DefaultEC2MaxAge  = 15 * time.Minute
+	DefaultEC2Region  = "us-east-1"
+)
+
+// Version of the EC2 API used by EC2Source.
+const ec2APIVersion = "2016-11-15"
+
+// AWSCredentials are the keys used to sign requests to AWS.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Only for temporary credentials
+}
+
+// ec2Instance is a running EC2 instance. It's also the format in which
+// instances are cached.
+type ec2Instance struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	KeyName    string            `json:"key_name"`
+	PrivateIP  string            `json:"private_ip"`
+	PrivateDNS string            `json:"private_dns"`
+	PublicIP   string            `json:"public_ip"`
+	PublicDNS  string            `json:"public_dns"`
+	Tags       map[string]string `json:"tags"`
+}
+
+// ec2Response is the response to a DescribeInstances request.
+type ec2Response struct {
+	Reservations []struct {
+		Instances []struct {
+			ID         string `xml:"instanceId"`
+			Type       string `xml:"instanceType"`
+			KeyName    string `xml:"keyName"`
+			PrivateIP  string `xml:"privateIpAddress"`
+			PrivateDNS string `xml:"privateDnsName"`
+			PublicIP   string `xml:"ipAddress"`
+			PublicDNS  string `xml:"dnsName"`
+			Tags       []struct {
+				Key   string `xml:"key"`
+				Value string `xml:"value"`
+			} `xml:"tagSet>item"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// ec2Error is an error response from the EC2 API.
+type ec2Error struct {
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Errors>Error"`
+}
+
+// EC2Source implements Source for the running EC2 instances of an AWS
+// profile in one region. Instances are named after their Name tag (or
+// their ID if they have none) and connect to their public IP address or
+// DNS name, or their private IP address if they have no public one.
+//
+// Credentials are read from the profile in ~/.aws/credentials (or
+// $AWS_SHARED_CREDENTIALS_FILE). The "default" profile falls back to the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables.
+//
+// The instances are cached for MaxAge.
+type EC2Source struct {
+	baseSource
+	Profile     string          // AWS profile
+	Region      string          // AWS region
+	Endpoint    string          // API URL; default is region's public endpoint
+	Credentials *AWSCredentials // Overrides profile's credentials
+	MaxAge      time.Duration   // How long to cache instances
+	Client      *http.Client
+	timeout     time.Duration
+}
+
+// NewEC2Source creates a new EC2Source for an AWS profile and region.
+// If region is empty, the profile's region from ~/.aws/config is used,
+// and if name is empty, the source is called "EC2 <profile>/<region>".
+func NewEC2Source(profile, region, name string, priority int, timeout time.Duration) *EC2Source {
+	if profile == "" {
+		profile = "default"
+	}
+	if region == "" {
+		region = awsProfileRegion(profile)
+	}
+	s := &EC2Source{
+		Profile: profile,
+		Region:  region,
+		MaxAge:  DefaultEC2MaxAge,
+		Client:  http.DefaultClient,
+		timeout: timeout,
+	}
+	s.name = name
+	if s.name == "" {
+		s.name = "EC2 " + profile + "/" + region
+	}
+	s.priority = priority
+	if s.timeout == 0 {
+		s.timeout = DefaultEC2Timeout
+	}
+	return s
+}
+
+// Timeout implements TimeoutSource.
+func (s *EC2Source) Timeout() time.Duration { return s.timeout }
+
+// Hosts implements Source.
+func (s *EC2Source) Hosts() []Host {
+	if s.hosts == nil {
+		s.HostsContext(context.Background())
+	}
+	return s.hosts
+}
+
+// HostsContext implements ContextSource.
+func (s *EC2Source) HostsContext(ctx context.Context) ([]Host, error) {
+	if s.hosts != nil {
+		return s.hosts, nil
+	}
+
+	key := s.Profile + "/" + s.Region + "@" + s.endpoint()
+	data, err := s.loadCachedData("ec2", key, s.MaxAge, func() ([]byte, error) {
+		instances, err := s.describeInstances(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(instances)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []ec2Instance
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return nil, err
+	}
+	s.hosts = []Host{}
+	for _, inst := range instances {
+		h := s.instanceHost(inst)
+		if h == nil {
+			continue
+		}
+		h.source = s.Name()
+		s.hosts = append(s.hosts, h)
+	}
+	log.Printf("[source/load/ec2] %d host(s) from '%s'", len(s.hosts), s.Name())
+	return s.hosts, nil
+}
+
+// instanceHost creates a Host for an instance. It returns nil if the
+// instance has no address.
+func (s *EC2Source) instanceHost(inst ec2Instance) *BaseHost {
+	var hostname string
+	for _, v := range []string{inst.PublicIP, inst.PublicDNS, inst.PrivateIP, inst.PrivateDNS} {
+		if v != "" {
+			hostname = v
+			break
+		}
+	}
+	if !IsValidHostname(hostname) {
+		log.Printf("[source/ec2/%s] no valid address for %s: %q", s.Name(), inst.ID, hostname)
+		return nil
+	}
+	name := inst.Tags["Name"]
+	if name == "" {
+		name = inst.ID
+	}
+
+	h := &BaseHost{name: name, hostname: hostname}
+	h.meta.AddTag("ec2", s.Region)
+	if s.Profile != "default" {
+		h.meta.AddTag(s.Profile)
+	}
+	for _, ip := range []string{inst.PublicIP, inst.PrivateIP} {
+		if net.ParseIP(ip) != nil {
+			h.meta.AddIP(ip)
+		}
+	}
+	h.meta.Description = strings.TrimSpace(inst.ID + " " + inst.Type)
+	h.meta.Set("instance_id", inst.ID)
+	if inst.KeyName != "" {
+		h.meta.Set("key_name", inst.KeyName)
+	}
+	if inst.PrivateDNS != "" {
+		h.meta.Set("private_dns", inst.PrivateDNS)
+	}
+	return h
+}
+
+// endpoint returns the URL of the EC2 API.
+func (s *EC2Source) endpoint() string {
+	if s.Endpoint != "" {
+		return s.Endpoint
+	}
+	return "https://ec2." + s.Region + ".amazonaws.com/"
+}
+
+// describeInstances fetches all running instances from the EC2 API.
+func (s *EC2Source) describeInstances(ctx context.Context) ([]ec2Instance, error) {
+	creds := s.Credentials
+	if creds == nil {
+		var err error
+		if creds, err = awsProfileCredentials(s.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		instances []ec2Instance
+		token     string
+		start     = time.Now()
+	)
+	for {
+		form := url.Values{}
+		form.Set("Action", "DescribeInstances")
+		form.Set("Version", ec2APIVersion)
+		form.Set("Filter.1.Name", "instance-state-name")
+		form.Set("Filter.1.Value.1", "running")
+		form.Set("MaxResults", "1000")
+		if token != "" {
+			form.Set("NextToken", token)
+		}
+		body := []byte(form.Encode())
+
+		req, err := http.NewRequestWithContext(ctx, "POST", s.endpoint(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		signAWSRequest(req, body, creds, s.Region, "ec2", time.Now())
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			var e ec2Error
+			if xml.Unmarshal(data, &e) == nil && len(e.Errors) > 0 {
+				return nil, fmt.Errorf("EC2 API: %s: %s", e.Errors[0].Code, e.Errors[0].Message)
+			}
+			return nil, fmt.Errorf("EC2 API: %s", resp.Status)
+		}
+
+		var r ec2Response
+		if err := xml.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("invalid EC2 response: %v", err)
+		}
+		for _, res := range r.Reservations {
+			for _, i := range res.Instances {
+				inst := ec2Instance{
+					ID:         i.ID,
+					Type:       i.Type,
+					KeyName:    i.KeyName,
+					PrivateIP:  i.PrivateIP,
+					PrivateDNS: i.PrivateDNS,
+					PublicIP:   i.PublicIP,
+					PublicDNS:  i.PublicDNS,
+					Tags:       map[string]string{},
+				}
+				for _, t := range i.Tags {
+					inst.Tags[t.Key] = t.Value
+				}
+				instances = append(instances, inst)
+			}
+		}
+		if token = r.NextToken; token == "" {
+			break
+		}
+	}
+	log.Printf("[source/ec2/%s] fetched %d instance(s) in %v", s.Name(), len(instances), time.Since(start))
+	return instances, nil
+}
+
+// signAWSRequest signs req with AWS Signature Version 4. body is the
+// request's payload. The Host header, X-Amz-* headers and any headers
+// already set on req are signed.
+func signAWSRequest(req *http.Request, body []byte, creds *AWSCredentials, region, service string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Canonical headers
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	var names []string
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+	payloadHash := sha256.Sum256(body)
+	canonRequest := strings.Join([]string{
+		req.Method,
+		path,
+		query,
+		canonHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsProfileCredentials returns the credentials of an AWS profile from
+// the shared credentials file. The environment is only used for the
+// "default" profile, so a misspelt profile isn't silently replaced by
+// the default account.
+func awsProfileCredentials(profile string) (*AWSCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), ".aws/credentials")
+	}
+	values := readAWSConfigSection(path, profile)
+	creds := &AWSCredentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}
+	if creds.AccessKeyID == "" && profile == "default" {
+		creds = &AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("no AWS credentials for profile " + profile)
+	}
+	return creds, nil
+}
+
+// awsProfileRegion returns the region of an AWS profile from the shared
+// config file, $AWS_REGION or $AWS_DEFAULT_REGION, or DefaultEC2Region.
+func awsProfileRegion(profile string) string {
+	path := os.Getenv("AWS_CONFIG_FILE")
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), ".aws/config")
+	}
+	section := "profile " + profile
+	if profile == "default" {
+		section = profile
+	}
+	if r := readAWSConfigSection(path, section)["region"]; r != "" {
+		return r
+	}
+	for _, k := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if r := os.Getenv(k); r != "" {
+			return r
+		}
+	}
+	return DefaultEC2Region
+}
+
+// readAWSConfigSection returns the key-value pairs in a section of an
+// AWS config or credentials file.
+func readAWSConfigSection(path, section string) map[string]string {
+	values := map[string]string{}
+	fp, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer fp.Close()
+
+	var inSection bool
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			inSection = strings.TrimSpace(strings.Trim(line, "[]")) == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if i := strings.Index(line, "="); i > 0 {
+			values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return values
+}
@@ -0,0 +1,194 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testEC2Page1 = `<?xml version="1.0" encoding="UTF-8"?>
+<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>8f7724cf-496f-496e-8fe3-example</requestId>
+  <reservationSet>
+    <item>
+      <reservationId>r-1234567890abcdef0</reservationId>
+      <instancesSet>
+        <item>
+          <instanceId>i-0598c7d356eba48d7</instanceId>
+          <instanceType>t3.micro</instanceType>
+          <keyName>deploy</keyName>
+          <privateDnsName>ip-10-0-1-12.ec2.internal</privateDnsName>
+          <dnsName>ec2-54-1-2-3.compute-1.amazonaws.com</dnsName>
+          <privateIpAddress>10.0.1.12</privateIpAddress>
+          <ipAddress>54.1.2.3</ipAddress>
+          <tagSet>
+            <item><key>Name</key><value>web1</value></item>
+            <item><key>env</key><value>prod</value></item>
+          </tagSet>
+        </item>
+      </instancesSet>
+    </item>
+  </reservationSet>
+  <nextToken>page2</nextToken>
+</DescribeInstancesResponse>`
+
+const testEC2Page2 = `<?xml version="1.0" encoding="UTF-8"?>
+<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <reservationSet>
+    <item>
+      <instancesSet>
+        <item>
+          <instanceId>i-0a1b2c3d4e5f60718</instanceId>
+          <instanceType>m5.large</instanceType>
+          <privateDnsName>ip-10-0-2-40.ec2.internal</privateDnsName>
+          <dnsName></dnsName>
+          <privateIpAddress>10.0.2.40</privateIpAddress>
+        </item>
+      </instancesSet>
+    </item>
+  </reservationSet>
+</DescribeInstancesResponse>`
+
+// TestEC2Source tests fetching instances from a fake EC2 API.
+func TestEC2Source(t *testing.T) {
+	var requests int
+	ts := testServer(t, "", "", "", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+			!strings.Contains(auth, "/eu-west-1/ec2/aws4_request") {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `<Response><Errors><Error><Code>AuthFailure</Code><Message>bad signature</Message></Error></Errors></Response>`)
+			return
+		}
+		if r.FormValue("Action") != "DescribeInstances" || r.FormValue("Filter.1.Value.1") != "running" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("NextToken") == "page2" {
+			fmt.Fprint(w, testEC2Page2)
+		} else {
+			fmt.Fprint(w, testEC2Page1)
+		}
+	})
+
+	creds := &AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}
+	s := NewEC2Source("work", "eu-west-1", "ec2", 1, time.Second)
+	s.Endpoint = ts.URL
+	s.Credentials = creds
+
+	hosts, err := s.HostsContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both pages are read, and instances without a public address use
+	// their private one
+	checkHosts(t, hosts, []testHost{
+		{"web1", "ssh://54.1.2.3", []string{"ec2", "eu-west-1", "work"}, "i-0598c7d356eba48d7 t3.micro"},
+		{"i-0a1b2c3d4e5f60718", "ssh://10.0.2.40", []string{"ec2", "eu-west-1", "work"}, "i-0a1b2c3d4e5f60718 m5.large"},
+	})
+	if x := []string{"54.1.2.3", "10.0.1.12"}; !reflect.DeepEqual(hosts[0].Meta().IPs, x) {
+		t.Errorf("Bad IPs. Expected=%v, Got=%v", x, hosts[0].Meta().IPs)
+	}
+	if x := hosts[0].Meta().Get("key_name"); x != "deploy" {
+		t.Errorf("Bad key_name. Expected=deploy, Got=%q", x)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, Got=%d", requests)
+	}
+
+	// API errors
+	s2 := NewEC2Source("work", "eu-west-1", "ec2", 1, time.Second)
+	s2.Endpoint = ts.URL
+	s2.Credentials = &AWSCredentials{AccessKeyID: "wrong", SecretAccessKey: "secret"}
+	_, err = s2.HostsContext(context.Background())
+	if x := "EC2 API: AuthFailure: bad signature"; err == nil || err.Error() != x {
+		t.Errorf("Bad error. Expected=%q, Got=%v", x, err)
+	}
+}
+
+// TestSignAWSRequest tests request signing against the "get-vanilla"
+// example from AWS's Signature Version 4 test suite.
+func TestSignAWSRequest(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := &AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	tm := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signAWSRequest(req, nil, creds, "us-east-1", "service", tm)
+
+	x := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if v := req.Header.Get("Authorization"); v != x {
+		t.Errorf("Bad signature. Expected=%q, Got=%q", x, v)
+	}
+}
+
+func TestReadAWSConfigSection(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"config": "[default]\nregion = us-east-1\n\n[profile work]\n# comment\nregion = eu-west-1\noutput=json\n",
+	})
+	path := filepath.Join(dir, "config")
+	x := map[string]string{"region": "eu-west-1", "output": "json"}
+	if v := readAWSConfigSection(path, "profile work"); !reflect.DeepEqual(v, x) {
+		t.Errorf("Expected=%v, Got=%v", x, v)
+	}
+
+	os.Setenv("AWS_CONFIG_FILE", path)
+	defer os.Unsetenv("AWS_CONFIG_FILE")
+	if v := awsProfileRegion("work"); v != "eu-west-1" {
+		t.Errorf("Bad region. Expected=eu-west-1, Got=%q", v)
+	}
+	if v := awsProfileRegion("default"); v != "us-east-1" {
+		t.Errorf("Bad region. Expected=us-east-1, Got=%q", v)
+	}
+}
+
+// TestAWSProfileCredentials tests that only the default profile falls
+// back to credentials in the environment.
+func TestAWSProfileCredentials(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"credentials": "[work]\naws_access_key_id = AKIDWORK\naws_secret_access_key = secret\n",
+	})
+	path := filepath.Join(dir, "credentials")
+	for k, v := range map[string]string{
+		"AWS_SHARED_CREDENTIALS_FILE": path,
+		"AWS_ACCESS_KEY_ID":           "AKIDENV",
+		"AWS_SECRET_ACCESS_KEY":       "secret",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	for profile, x := range map[string]string{"work": "AKIDWORK", "default": "AKIDENV"} {
+		creds, err := awsProfileCredentials(profile)
+		if err != nil {
+			t.Errorf("[%s] Unexpected error: %v", profile, err)
+		} else if creds.AccessKeyID != x {
+			t.Errorf("[%s] Expected=%s, Got=%s", profile, x, creds.AccessKeyID)
+		}
+	}
+	if _, err := awsProfileCredentials("wrok"); err == nil {
+		t.Error("Accepted unknown profile")
+	}
+}
@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// FileZilla's protocol number for SFTP.
+const filezillaSFTP = 1
+
+// filezillaFolder is a folder in FileZilla's Site Manager. Its name is
+// the element's text.
+type filezillaFolder struct {
+	Name    string            `xml:",chardata"`
+	Folders []filezillaFolder `xml:"Folder"`
+	Servers []filezillaServer `xml:"Server"`
+}
+
+// filezillaServer is a site in FileZilla's Site Manager.
+type filezillaServer struct {
+	Name      string `xml:"Name"`
+	Host      string `xml:"Host"`
+	Port      int    `xml:"Port"`
+	Protocol  int    `xml:"Protocol"`
+	User      string `xml:"User"`
+	RemoteDir string `xml:"RemoteDir"`
+	Comments  string `xml:"Comments"`
+}
+
+// FileZillaSource implements Source for FileZilla's sitemanager.xml.
+// Only SFTP sites are read. The folders a site is in become its tags,
+// and its default remote directory is kept in Meta.RemoteDir. Hosts'
+// default action is SFTP (see Meta.Action).
+type FileZillaSource struct {
+	baseSource
+}
+
+// NewFileZillaSource creates a new FileZillaSource for a sitemanager.xml file.
+func NewFileZillaSource(path, name string, priority int) *FileZillaSource {
+	s := &FileZillaSource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *FileZillaSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("filezilla", func() ([]Host, []string) {
+			hosts, err := readFileZillaSites(s.Filepath)
+			if err != nil {
+				log.Printf("[filezilla/%s] %v", s.Filepath, err)
+			}
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, nil
+		})
+	}
+	return s.hosts
+}
+
+// readFileZillaSites reads the SFTP sites from sitemanager.xml.
+func readFileZillaSites(path string) ([]*BaseHost, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Servers filezillaFolder `xml:"Servers"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Servers.hosts(nil), nil
+}
+
+// hosts returns the SFTP sites in folder f and its subfolders. folders
+// are the names of the parent folders.
+func (f filezillaFolder) hosts(folders []string) []*BaseHost {
+	var hosts []*BaseHost
+	for _, srv := range f.Servers {
+		if srv.Protocol != filezillaSFTP {
+			continue
+		}
+		host := strings.TrimSpace(srv.Host)
+		if !IsValidHostname(host) {
+			log.Printf("[filezilla] invalid hostname for %q: %q", srv.Name, host)
+			continue
+		}
+		name := strings.TrimSpace(srv.Name)
+		if name == "" {
+			name = host
+		}
+		h := &BaseHost{name: name, hostname: host, username: srv.User, port: srv.Port}
+		h.meta.AddTag(folders...)
+		h.meta.Description = strings.TrimSpace(srv.Comments)
+		h.meta.RemoteDir = parseFileZillaPath(srv.RemoteDir)
+		h.meta.Action = ActionSFTP
+		if net.ParseIP(host) != nil {
+			h.meta.AddIP(host)
+		}
+		hosts = append(hosts, h)
+	}
+
+	for _, sub := range f.Folders {
+		name := strings.TrimSpace(sub.Name)
+		path := append(append([]string{}, folders...), name)
+		hosts = append(hosts, sub.hosts(path)...)
+	}
+	return hosts
+}
+
+// parseFileZillaPath decodes FileZilla's serialised path format, e.g.
+// "1 0 4 home 3 bob" for the Unix path /home/bob. The fields are the
+// server type, the length of a prefix and the prefix (if any), followed
+// by each path segment preceded by its length. Non-Unix paths (server
+// type other than 1) are returned as an empty string.
+func parseFileZillaPath(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	s += " " // so every field is followed by a space
+
+	// next reads a length-prefixed string from s.
+	next := func() (string, bool) {
+		i := strings.Index(s, " ")
+		if i < 0 {
+			return "", false
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil || n < 0 || i+1+n > len(s) {
+			return "", false
+		}
+		v := s[i+1 : i+1+n]
+		s = strings.TrimPrefix(s[i+1+n:], " ")
+		return v, true
+	}
+
+	i := strings.Index(s, " ")
+	if s[:i] != "1" {
+		return ""
+	}
+	s = s[i+1:]
+	if _, ok := next(); !ok { // prefix
+		return ""
+	}
+
+	var segments []string
+	for s != "" {
+		seg, ok := next()
+		if !ok {
+			return ""
+		}
+		segments = append(segments, seg)
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,87 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+var filezillaData = `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<FileZilla3 version="3.66.1" platform="mac">
+  <Servers>
+    <Server>
+      <Host>files.example.com</Host>
+      <Port>22</Port>
+      <Protocol>1</Protocol>
+      <User>designer</User>
+      <Name>Assets</Name>
+      <RemoteDir>1 0 3 srv 6 assets</RemoteDir>
+    </Server>
+    <Folder expanded="1">Clients
+      <Server>
+        <Host>ftp.example.com</Host>
+        <Port>21</Port>
+        <Protocol>0</Protocol>
+        <Name>Old FTP</Name>
+      </Server>
+      <Folder>Acme
+        <Server>
+          <Host>10.0.0.9</Host>
+          <Port>2222</Port>
+          <Protocol>1</Protocol>
+          <User>acme</User>
+          <Name>Acme staging</Name>
+          <Comments>Staging site</Comments>
+          <RemoteDir></RemoteDir>
+        </Server>
+      </Folder>
+    </Folder>
+  </Servers>
+</FileZilla3>`
+
+// TestFileZillaSource tests reading SFTP sites from sitemanager.xml.
+func TestFileZillaSource(t *testing.T) {
+	dir := testDir(t, map[string]string{"sitemanager.xml": filezillaData})
+	hosts := NewFileZillaSource(filepath.Join(dir, "sitemanager.xml"), "FileZilla", 1).Hosts()
+
+	// Only SFTP sites are included, tagged with their folders
+	checkHosts(t, hosts, []testHost{
+		{"Assets", "ssh://designer@files.example.com", nil, ""},
+		{"Acme staging", "ssh://acme@10.0.0.9:2222", []string{"Clients", "Acme"}, "Staging site"},
+	})
+	if x, v := "sftp://designer@files.example.com/srv/assets", hosts[0].SFTPURL().String(); v != x {
+		t.Errorf("Bad SFTP URL. Expected=%q, Got=%q", x, v)
+	}
+	for _, h := range hosts {
+		// "action" is the workflow's routing variable
+		vars := h.Meta().Vars()
+		if _, ok := vars["action"]; ok || vars["default_action"] != ActionSFTP {
+			t.Errorf("Bad variables. Expected default_action=%q, Got=%v", ActionSFTP, vars)
+		}
+	}
+}
+
+func TestParseFileZillaPath(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"1 0", "/"},
+		{"1 0 4 home 3 bob", "/home/bob"},
+		{"1 0 4 my d 4 ir s", "/my d/ir s"},
+		{"8 0 2 C: 5 Users", ""}, // Windows
+		{"1 0 9 short", ""},      // Malformed
+	}
+	for _, td := range tests {
+		if s := parseFileZillaPath(td.in); s != td.out {
+			t.Errorf("Bad path for %q. Expected=%q, Got=%q", td.in, td.out, s)
+		}
+	}
+}
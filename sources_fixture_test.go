@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testDir creates a temporary directory containing files, which maps
+// paths relative to the directory to their contents. The directory is
+// deleted when the test ends.
+func testDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "assh-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	writeTestFiles(t, dir, files)
+	return dir
+}
+
+// writeTestFiles writes files to dir, creating parent directories as
+// needed. Files whose contents start with "#!" are made executable.
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		mode := os.FileMode(0600)
+		if len(data) > 1 && data[:2] == "#!" {
+			mode = 0700
+		}
+		if err := ioutil.WriteFile(path, []byte(data), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testServer starts an HTTP server, which is stopped when the test
+// ends. If header is set, requests without that header and value are
+// answered with 403 Forbidden and denied as the body.
+func testServer(t *testing.T, header, value, denied string, handler http.HandlerFunc) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header != "" && r.Header.Get(header) != value {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, denied)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// testHost is the properties of a Host that source tests compare.
+type testHost struct {
+	Name        string
+	URL         string // SSH URL, i.e. username, hostname and port
+	Tags        []string
+	Description string
+}
+
+// checkHosts compares hosts to expected.
+func checkHosts(t *testing.T, hosts []Host, expected []testHost) {
+	t.Helper()
+	var v []testHost
+	for _, h := range hosts {
+		v = append(v, testHost{h.Name(), h.SSHURL().String(), h.Meta().Tags, h.Meta().Description})
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected=%+v, Got=%+v", expected, v)
+	}
+}
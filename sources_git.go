@@ -0,0 +1,257 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultGitScanDepth is how many levels of subdirectories GitSource
+// searches for repositories.
+const DefaultGitScanDepth = 3
+
+// Directories GitSource doesn't search for repositories.
+var gitSkipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "Library": true,
+}
+
+// gitRemote is an SSH remote of a repository.
+type gitRemote struct {
+	user, host string
+	port       int
+	path       string // Path of repo on server, e.g. "team/repo.git"
+}
+
+// GitSource implements Source for the SSH remotes of the git repositories
+// in a directory and its subdirectories (up to MaxDepth levels deep).
+// Each distinct server is one Host, whose description lists the remote
+// repositories on it. The paths of the local repositories are stored in
+// the "repos" Meta value, separated by colons.
+type GitSource struct {
+	baseSource
+	MaxDepth int // How many levels of subdirectories to search
+}
+
+// NewGitSource creates a new GitSource for a directory.
+func NewGitSource(path, name string, priority int) *GitSource {
+	s := &GitSource{MaxDepth: DefaultGitScanDepth}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *GitSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("git", func() ([]Host, []string) {
+			hosts, files := scanGitRepos(s.Filepath, s.MaxDepth)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, files
+		})
+	}
+	return s.hosts
+}
+
+// scanGitRepos finds the git repositories in dir and returns a Host for
+// each server their SSH remotes point to. It also returns the config
+// files it read and the directories it searched, so caches notice new
+// repositories.
+func scanGitRepos(dir string, maxDepth int) ([]*BaseHost, []string) {
+	var (
+		hosts []*BaseHost
+		files []string
+		index = map[string]*BaseHost{}   // user@host:port -> Host
+		repos = map[*BaseHost][]string{} // Local repositories
+		paths = map[*BaseHost][]string{} // Remote repositories
+	)
+
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		if config := gitConfigPath(dir); config != "" {
+			files = append(files, config)
+			for _, r := range readGitRemotes(config) {
+				key := r.user + "@" + HostPort(r.host, r.port)
+				h, ok := index[key]
+				if !ok {
+					h = &BaseHost{name: r.host, hostname: r.host, username: r.user, port: r.port}
+					h.meta.AddTag("git")
+					if net.ParseIP(r.host) != nil {
+						h.meta.AddIP(r.host)
+					}
+					index[key] = h
+					hosts = append(hosts, h)
+				}
+				name := strings.TrimSuffix(r.path, ".git")
+				h.meta.AddTag(name)
+				paths[h] = appendUnique(paths[h], name)
+				repos[h] = appendUnique(repos[h], dir)
+			}
+			return // don't search inside repositories
+		}
+		if depth > maxDepth {
+			return
+		}
+
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			log.Printf("[git/%s] %v", dir, err)
+			return
+		}
+		files = append(files, dir)
+		for _, fi := range infos {
+			name := fi.Name()
+			if !fi.IsDir() || strings.HasPrefix(name, ".") || gitSkipDirs[name] {
+				continue
+			}
+			walk(filepath.Join(dir, name), depth+1)
+		}
+	}
+	walk(dir, 0)
+
+	for _, h := range hosts {
+		names := paths[h]
+		sort.Strings(names)
+		h.meta.Description = strings.Join(names, ", ")
+		h.meta.Set("repos", strings.Join(repos[h], string(os.PathListSeparator)))
+	}
+	return hosts, files
+}
+
+// gitConfigPath returns the path of the config file of the repository in
+// dir, or an empty string if dir isn't a repository. In worktrees and
+// submodules, .git is a file pointing to the real git directory, and a
+// worktree's git directory points to the main repository's in commondir.
+func gitConfigPath(dir string) string {
+	gitDir := filepath.Join(dir, ".git")
+	fi, err := os.Stat(gitDir)
+	if err != nil {
+		return ""
+	}
+	if !fi.IsDir() {
+		data, err := ioutil.ReadFile(gitDir)
+		if err != nil {
+			log.Printf("[git/%s] %v", gitDir, err)
+			return ""
+		}
+		s := strings.TrimSpace(string(data))
+		if !strings.HasPrefix(s, "gitdir:") {
+			return ""
+		}
+		gitDir = resolveGitPath(dir, strings.TrimPrefix(s, "gitdir:"))
+		if data, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+			gitDir = resolveGitPath(gitDir, string(data))
+		}
+	}
+	config := filepath.Join(gitDir, "config")
+	if _, err := os.Stat(config); err != nil {
+		return ""
+	}
+	return config
+}
+
+// resolveGitPath resolves a path read from a file in git directory dir.
+// Relative paths are relative to dir.
+func resolveGitPath(dir, path string) string {
+	path = strings.TrimSpace(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// readGitRemotes returns the SSH remotes in a repository's config file.
+func readGitRemotes(path string) []gitRemote {
+	fp, err := os.Open(path)
+	if err != nil {
+		log.Printf("[git/%s] %v", path, err)
+		return nil
+	}
+	defer fp.Close()
+
+	var (
+		remotes  []gitRemote
+		inRemote bool
+		scanner  = bufio.NewScanner(fp)
+	)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			inRemote = strings.HasPrefix(line, "[remote ")
+			continue
+		}
+		if !inRemote {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		if key != "url" && key != "pushurl" {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(line[i+1:]), `"`)
+		if r, ok := parseGitRemote(value); ok {
+			remotes = append(remotes, r)
+		}
+	}
+	return remotes
+}
+
+// parseGitRemote parses an SSH remote URL, i.e. ssh://[user@]host[:port]/path
+// or the scp-like [user@]host:path. ok is false for other URLs and
+// local paths.
+func parseGitRemote(s string) (r gitRemote, ok bool) {
+	if i := strings.Index(s, "://"); i > -1 {
+		switch s[:i] {
+		case "ssh", "git+ssh", "ssh+git":
+		default:
+			return r, false
+		}
+		u, err := url.Parse(s)
+		if err != nil || u.Hostname() == "" {
+			return r, false
+		}
+		r.host = u.Hostname()
+		if u.User != nil {
+			r.user = u.User.Username()
+		}
+		if p, err := strconv.Atoi(u.Port()); err == nil {
+			r.port = p
+		}
+		r.path = strings.TrimPrefix(u.Path, "/")
+	} else {
+		// scp-like syntax. git treats it as a local path if there's a
+		// slash before the first colon.
+		host, ok := splitSCPTarget(s)
+		if !ok {
+			return r, false
+		}
+		r.user, r.host, _ = ParseQuery(host)
+		r.path = strings.TrimPrefix(s[len(host)+1:], "/")
+	}
+	r.path = strings.TrimPrefix(r.path, "~/")
+	return r, IsValidHostname(r.host)
+}
@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitRemote(t *testing.T) {
+	tests := []struct {
+		in   string
+		out  gitRemote
+		isOK bool
+	}{
+		{"git@git.internal:team/repo.git", gitRemote{"git", "git.internal", 0, "team/repo.git"}, true},
+		{"git.internal:repo", gitRemote{"", "git.internal", 0, "repo"}, true},
+		{"ssh://git@git.internal:2222/team/repo.git", gitRemote{"git", "git.internal", 2222, "team/repo.git"}, true},
+		{"git+ssh://bob@[2001:db8::5]/~/repo", gitRemote{"bob", "2001:db8::5", 0, "repo"}, true},
+		{"https://github.com/team/repo.git", gitRemote{}, false},
+		{"/srv/git/repo.git", gitRemote{}, false},
+		{"./foo:bar", gitRemote{}, false},
+		{"file:///srv/git/repo.git", gitRemote{}, false},
+	}
+	for _, td := range tests {
+		r, ok := parseGitRemote(td.in)
+		if ok != td.isOK || (ok && r != td.out) {
+			t.Errorf("Bad remote for %q. Expected=%+v/%v, Got=%+v/%v", td.in, td.out, td.isOK, r, ok)
+		}
+	}
+}
+
+// TestGitSource tests finding remotes in a directory tree.
+func TestGitSource(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"app/.git/config": "[core]\n\tbare = false\n[remote \"origin\"]\n" +
+			"\turl = git@git.internal:team/app.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n" +
+			"[remote \"github\"]\n\turl = https://github.com/team/app.git\n",
+		"work/lib/.git/config":        "[remote \"origin\"]\n\turl = ssh://git@git.internal/team/lib.git\n",
+		"work/lib/vendor/.git/config": "[remote \"origin\"]\n\turl = git@nested.example.com:x.git\n",
+		"work/tool/.git/config":       "[remote \"origin\"]\n\turl = ops@10.0.0.5:tool\n",
+		// Worktree and submodule with .git files
+		"app-feature/.git":                     "gitdir: ../app/.git/worktrees/feature\n",
+		"app/.git/worktrees/feature/commondir": "../..\n",
+		"work/svc/.git":                        "gitdir: ../../app/.git/modules/svc\n",
+		"app/.git/modules/svc/config":          "[remote \"origin\"]\n\turl = git@git.internal:team/svc.git\n",
+		"node_modules/x/.git/config":           "[remote \"origin\"]\n\turl = git@skipped.example.com:x.git\n",
+	})
+
+	type tGitHost struct {
+		URL, Description, Repos string
+	}
+	expected := []tGitHost{
+		{"ssh://git@git.internal", "team/app, team/lib, team/svc", strings.Join([]string{
+			filepath.Join(dir, "app"), filepath.Join(dir, "app-feature"),
+			filepath.Join(dir, "work/lib"), filepath.Join(dir, "work/svc"),
+		}, string(os.PathListSeparator))},
+		{"ssh://ops@10.0.0.5", "tool", filepath.Join(dir, "work/tool")},
+	}
+
+	var v []tGitHost
+	for _, h := range NewGitSource(dir, "git", 1).Hosts() {
+		v = append(v, tGitHost{h.SSHURL().String(), h.Meta().Description, h.Meta().Get("repos")})
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected=%+v, Got=%+v", expected, v)
+	}
+}
@@ -22,9 +22,16 @@ type historyEntry struct {
 }
 
 // History is a list of previously opened URLs.
+//
+// If SyncDir is set, changes are also written to a per-machine,
+// append-only journal in that directory, and the journals of all
+// machines are merged when the History is loaded.
 type History struct {
 	baseSource
-	d *Deduplicator
+	SyncDir string        // Directory shared between machines
+	Machine string        // Name of this machine's journal
+	Dedup   DedupStrategy // Which entries are duplicates
+	d       DuplicateFilter
 }
 
 // NewHistory initialises a new History struct. You must call History.Load()
@@ -34,13 +41,14 @@ func NewHistory(path, name string, priority int) *History {
 	h.Filepath = path
 	h.name = name
 	h.priority = priority
-	h.d = &Deduplicator{}
+	h.d = NewDuplicateFilter(h.Dedup)
 	return h
 }
 
-// Add adds an item to the History.
+// Add adds an item to the History. The same URL is never added twice,
+// even if the DedupStrategy is DedupNone.
 func (h *History) Add(host Host) error {
-	if h.d.IsDuplicate(host) {
+	if h.d.IsDuplicate(host) || h.contains(host) {
 		log.Printf("[history/%s] Ignoring duplicate: %v", h.Filepath, host)
 		return nil
 	}
@@ -50,9 +58,26 @@ func (h *History) Add(host Host) error {
 
 	log.Printf("Adding %s to history ...", host.Name())
 
+	if h.SyncDir != "" {
+		if err := h.appendJournal(journalAdd, host.SSHURL().String()); err != nil {
+			return err
+		}
+	}
+
 	return h.Save()
 }
 
+// contains returns true if History contains a Host with the same URL.
+func (h *History) contains(host Host) bool {
+	u := host.SSHURL().String()
+	for _, xh := range h.hosts {
+		if xh.SSHURL().String() == u {
+			return true
+		}
+	}
+	return false
+}
+
 // Remove removes an item from the History.
 func (h *History) Remove(host Host) error {
 	for i, xh := range h.hosts {
@@ -62,6 +87,11 @@ func (h *History) Remove(host Host) error {
 		if xh.SSHURL().String() == host.SSHURL().String() {
 			h.hosts = append(h.hosts[0:i], h.hosts[i+1:]...)
 			log.Printf("Removed '%s' from history", host.Name())
+			if h.SyncDir != "" {
+				if err := h.appendJournal(journalRemove, xh.SSHURL().String()); err != nil {
+					return err
+				}
+			}
 			return h.Save()
 		}
 	}
@@ -80,20 +110,23 @@ func (h *History) Hosts() []Host {
 
 // Load loads the history from disk.
 func (h *History) Load() error {
-	if _, err := os.Stat(h.Filepath); err != nil {
-		return nil
-	}
-
-	urls := []string{}
-	data, err := ioutil.ReadFile(h.Filepath)
+	urls, err := h.readLocal()
 	if err != nil {
 		return err
 	}
-	if err = json.Unmarshal(data, &urls); err != nil {
-		return err
+
+	if h.SyncDir != "" {
+		if err := h.seedJournal(urls); err != nil {
+			return err
+		}
+		if urls, err = h.loadJournals(); err != nil {
+			return err
+		}
 	}
-	h.hosts = make([]Host, len(urls))
-	for i, s := range urls {
+
+	h.hosts = []Host{}
+	h.d = NewDuplicateFilter(h.Dedup)
+	for _, s := range urls {
 		u, err := url.Parse(s)
 		if err != nil {
 			return err
@@ -101,7 +134,7 @@ func (h *History) Load() error {
 		host := NewBaseHostFromURL(u)
 		host.source = h.Name()
 		if !h.d.IsDuplicate(host) {
-			h.hosts[i] = host
+			h.hosts = append(h.hosts, host)
 			h.d.Add(host)
 		}
 	}
@@ -109,6 +142,23 @@ func (h *History) Load() error {
 	return nil
 }
 
+// readLocal reads URLs from the local history file.
+func (h *History) readLocal() ([]string, error) {
+	urls := []string{}
+	if _, err := os.Stat(h.Filepath); err != nil {
+		return urls, nil
+	}
+
+	data, err := ioutil.ReadFile(h.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
 // Save saves the History to disk.
 func (h *History) Save() error {
 
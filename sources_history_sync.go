@@ -0,0 +1,214 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Journal operations.
+const (
+	journalAdd    = "add"
+	journalRemove = "remove"
+)
+
+// Extension of per-machine journal files.
+const journalExt = ".jsonl"
+
+var unsafeMachineChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// journalEntry is a single change to a synchronised History.
+type journalEntry struct {
+	Op      string    `json:"op"`      // journalAdd or journalRemove
+	URL     string    `json:"url"`     // URL of host
+	Time    time.Time `json:"time"`    // When change was made
+	Machine string    `json:"machine"` // Machine change was made on
+}
+
+// DefaultMachineID returns an identifier for this computer that is safe
+// to use as a filename.
+func DefaultMachineID() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "unknown"
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return unsafeMachineChars.ReplaceAllString(name, "_")
+}
+
+// journalPath returns the path of this machine's journal.
+func (h *History) journalPath() string {
+	if h.Machine == "" {
+		h.Machine = DefaultMachineID()
+	}
+	return filepath.Join(h.SyncDir, h.Machine+journalExt)
+}
+
+// appendJournal appends an entry to this machine's journal.
+func (h *History) appendJournal(op string, entries ...string) error {
+	if err := os.MkdirAll(h.SyncDir, 0700); err != nil {
+		return err
+	}
+	fp, err := os.OpenFile(h.journalPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	now := time.Now().UTC()
+	for _, s := range entries {
+		data, err := json.Marshal(journalEntry{op, s, now, h.Machine})
+		if err != nil {
+			return err
+		}
+		if _, err := fp.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedJournal creates this machine's journal from the local history file
+// the first time synchronisation is enabled.
+func (h *History) seedJournal(urls []string) error {
+	if _, err := os.Stat(h.journalPath()); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(h.SyncDir, 0700); err != nil {
+		return err
+	}
+
+	// Date entries by the local file, so removals made on other
+	// machines since then still take precedence.
+	ts := time.Now().UTC()
+	if fi, err := os.Stat(h.Filepath); err == nil {
+		ts = fi.ModTime().UTC()
+	}
+
+	var b strings.Builder
+	for _, s := range urls {
+		data, err := json.Marshal(journalEntry{journalAdd, s, ts, h.Machine})
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+
+	log.Printf("[history/sync] seeding journal '%s' with %d URL(s)", h.journalPath(), len(urls))
+	return ioutil.WriteFile(h.journalPath(), []byte(b.String()), 0600)
+}
+
+// loadJournals reads and merges all journals in the sync directory
+// into a list of URLs.
+func (h *History) loadJournals() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(h.SyncDir, "*"+journalExt))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []journalEntry
+	for _, p := range paths {
+		e, err := readJournal(p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+
+	urls := mergeJournals(entries)
+	log.Printf("[history/sync] %d URL(s) from %d change(s) in %d journal(s)",
+		len(urls), len(entries), len(paths))
+	return urls, nil
+}
+
+// readJournal reads entries from a journal file. Invalid lines (e.g. a
+// partially-synced last line) are ignored.
+func readJournal(path string) ([]journalEntry, error) {
+	var entries []journalEntry
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	scanner := bufio.NewScanner(fp)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		e := journalEntry{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			log.Printf("[history/sync/%s] invalid entry on line %d: %v", filepath.Base(path), n, err)
+			continue
+		}
+		if e.Op != journalAdd && e.Op != journalRemove {
+			log.Printf("[history/sync/%s] unknown operation on line %d: %q", filepath.Base(path), n, e.Op)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// mergeJournals replays journal entries in chronological order and returns
+// the URLs that remain, ordered by when they were (last) added.
+//
+// Ties are broken by machine, then operation, so every machine arrives at
+// the same result regardless of the order in which it read the journals.
+func mergeJournals(entries []journalEntry) []string {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.Machine != b.Machine {
+			return a.Machine < b.Machine
+		}
+		return a.Op < b.Op
+	})
+
+	added := map[string]time.Time{}
+	for _, e := range entries {
+		switch e.Op {
+		case journalAdd:
+			if _, ok := added[e.URL]; !ok {
+				added[e.URL] = e.Time
+			}
+		case journalRemove:
+			delete(added, e.URL)
+		}
+	}
+
+	urls := make([]string, 0, len(added))
+	for s := range added {
+		urls = append(urls, s)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		a, b := added[urls[i]], added[urls[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return urls[i] < urls[j]
+	})
+	return urls
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMergeJournals tests that journals converge regardless of order.
+func TestMergeJournals(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []journalEntry{
+		{journalAdd, "ssh://a", t0, "laptop"},
+		{journalAdd, "ssh://b", t0.Add(time.Second), "desktop"},
+		{journalRemove, "ssh://a", t0.Add(2 * time.Second), "desktop"},
+		{journalAdd, "ssh://c", t0.Add(3 * time.Second), "laptop"},
+		{journalAdd, "ssh://a", t0.Add(4 * time.Second), "laptop"},
+		// Simultaneous add and remove: remove wins
+		{journalAdd, "ssh://d", t0.Add(5 * time.Second), "laptop"},
+		{journalRemove, "ssh://d", t0.Add(5 * time.Second), "laptop"},
+	}
+	expected := []string{"ssh://b", "ssh://c", "ssh://a"}
+
+	// Reverse order of entries
+	reversed := make([]journalEntry, len(entries))
+	for i, e := range entries {
+		reversed[len(entries)-1-i] = e
+	}
+
+	for _, l := range [][]journalEntry{entries, reversed} {
+		urls := mergeJournals(l)
+		if len(urls) != len(expected) {
+			t.Fatalf("Expected=%v, Got=%v", expected, urls)
+		}
+		for i, s := range urls {
+			if s != expected[i] {
+				t.Errorf("[%d] Expected=%v, Got=%v", i, expected[i], s)
+			}
+		}
+	}
+}
+
+// TestHistorySync tests History synchronisation between two machines.
+func TestHistorySync(t *testing.T) {
+	dir := testDir(t, nil)
+
+	syncDir := filepath.Join(dir, "sync")
+	newHistory := func(machine string) *History {
+		h := NewHistory(filepath.Join(dir, machine+".json"), "history", 1)
+		h.SyncDir = syncDir
+		h.Machine = machine
+		if err := h.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	host := func(s string) Host {
+		u, _ := url.Parse(s)
+		return NewBaseHostFromURL(u)
+	}
+
+	a := newHistory("a")
+	if err := a.Add(host("ssh://user@one.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	b := newHistory("b")
+	if err := b.Add(host("ssh://two.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Remove(host("ssh://user@one.example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range []*History{newHistory("a"), newHistory("b")} {
+		checkHosts(t, h.Hosts(), []testHost{{"two.example.com", "ssh://two.example.com", nil, ""}})
+	}
+}
@@ -33,13 +33,14 @@ func NewHostsSource(path, name string, priority int) *HostsSource {
 // Hosts implements Source.
 func (s *HostsSource) Hosts() []Host {
 	if s.hosts == nil {
-		hosts := readHostsFile(s.Filepath)
-		log.Printf("[source/load/hosts] %d host(s) in '%s'", len(hosts), s.Name())
-		s.hosts = make([]Host, len(hosts))
-		for i, h := range hosts {
-			h.source = s.Name()
-			s.hosts[i] = Host(h)
-		}
+		s.hosts = s.loadHosts("hosts", func() ([]Host, []string) {
+			hosts := readHostsFile(s.Filepath)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, nil
+		})
 	}
 	return s.hosts
 }
@@ -88,6 +89,7 @@ func readHostsFile(path string) []*BaseHost {
 				continue
 			}
 			h := &BaseHost{name: s, hostname: s}
+			h.meta.AddIP(fields[0])
 			hosts = append(hosts, h)
 		}
 	}
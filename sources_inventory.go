@@ -0,0 +1,184 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// inventoryGroup is a group of hosts in an inventory file. The top level
+// of the file is also a group (without a name).
+//
+//	user: admin
+//	groups:
+//	  - name: prod
+//	    user: deploy
+//	    tags: [web]
+//	    hosts:
+//	      - name: web1
+//	        hostname: 10.0.0.7
+//	        note: Main web server
+//	    groups:
+//	      - name: db
+//	        port: 2222
+//	        hosts:
+//	          - hostname: db1.example.com
+//
+// Hosts inherit the user, port, tags and note of their groups, and the
+// names of the groups are added to their tags.
+type inventoryGroup struct {
+	Name   string           `json:"name" yaml:"name" toml:"name"`
+	User   string           `json:"user" yaml:"user" toml:"user"`
+	Port   int              `json:"port" yaml:"port" toml:"port"`
+	Tags   []string         `json:"tags" yaml:"tags" toml:"tags"`
+	Note   string           `json:"note" yaml:"note" toml:"note"`
+	Hosts  []inventoryHost  `json:"hosts" yaml:"hosts" toml:"hosts"`
+	Groups []inventoryGroup `json:"groups" yaml:"groups" toml:"groups"`
+}
+
+// inventoryHost is a host in an inventory file. Either name or hostname
+// must be set; each defaults to the other.
+type inventoryHost struct {
+	Name     string   `json:"name" yaml:"name" toml:"name"`
+	Hostname string   `json:"hostname" yaml:"hostname" toml:"hostname"`
+	User     string   `json:"user" yaml:"user" toml:"user"`
+	Port     int      `json:"port" yaml:"port" toml:"port"`
+	Tags     []string `json:"tags" yaml:"tags" toml:"tags"`
+	Note     string   `json:"note" yaml:"note" toml:"note"`
+}
+
+// InventorySource implements Source for a hand-written YAML, TOML or JSON
+// file of hosts and (nested) groups of hosts. The format is determined by
+// the file extension.
+type InventorySource struct {
+	baseSource
+}
+
+// NewInventorySource creates a new InventorySource for an inventory file.
+func NewInventorySource(path, name string, priority int) *InventorySource {
+	s := &InventorySource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *InventorySource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("inventory", func() ([]Host, []string) {
+			hosts, err := readInventoryFile(s.Filepath)
+			if err != nil {
+				log.Printf("[inventory/%s] %v", s.Filepath, err)
+			}
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, nil
+		})
+	}
+	return s.hosts
+}
+
+// readInventoryFile reads hosts from an inventory file.
+func readInventoryFile(path string) ([]*BaseHost, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys are an error in all formats, so typos aren't ignored
+	var root inventoryGroup
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &root)
+	case ".toml":
+		var md toml.MetaData
+		if md, err = toml.Decode(string(data), &root); err == nil {
+			if keys := md.Undecoded(); len(keys) > 0 {
+				err = fmt.Errorf("unknown key(s): %v", keys)
+			}
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&root)
+	default:
+		err = fmt.Errorf("unknown file type: %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return root.hosts(inventoryGroup{}), nil
+}
+
+// hosts returns the hosts of group g and its subgroups. parent contains
+// the inherited settings.
+func (g inventoryGroup) hosts(parent inventoryGroup) []*BaseHost {
+	var hosts []*BaseHost
+
+	if g.User == "" {
+		g.User = parent.User
+	}
+	if g.Port == 0 {
+		g.Port = parent.Port
+	}
+	if g.Note == "" {
+		g.Note = parent.Note
+	}
+	g.Tags = appendUnique(append([]string{}, parent.Tags...), append(g.Tags, g.Name)...)
+
+	for _, ih := range g.Hosts {
+		if ih.Name == "" {
+			ih.Name = ih.Hostname
+		}
+		if ih.Hostname == "" {
+			ih.Hostname = ih.Name
+		}
+		if !IsValidHostname(ih.Hostname) {
+			log.Printf("[inventory] invalid hostname: %q", ih.Hostname)
+			continue
+		}
+		if ih.User == "" {
+			ih.User = g.User
+		}
+		if ih.Port == 0 {
+			ih.Port = g.Port
+		}
+
+		h := &BaseHost{name: ih.Name, hostname: ih.Hostname, username: ih.User, port: ih.Port}
+		h.meta.AddTag(g.Tags...)
+		h.meta.AddTag(ih.Tags...)
+		h.meta.Description = ih.Note
+		if h.meta.Description == "" {
+			h.meta.Description = g.Note
+		}
+		if net.ParseIP(ih.Hostname) != nil {
+			h.meta.AddIP(ih.Hostname)
+		}
+		hosts = append(hosts, h)
+	}
+
+	for _, sub := range g.Groups {
+		hosts = append(hosts, sub.hosts(g)...)
+	}
+	return hosts
+}
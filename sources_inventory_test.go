@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+var inventoryTests = map[string]string{
+	"hosts.yaml": `
+user: admin
+hosts:
+  - name: gateway
+    hostname: 192.168.1.1
+groups:
+  - name: prod
+    user: deploy
+    tags: [team]
+    note: Production
+    hosts:
+      - name: web1
+        hostname: 10.0.0.7
+        note: Main web server
+    groups:
+      - name: db
+        port: 2222
+        hosts:
+          - hostname: db1.example.com
+            tags: [postgres]
+`,
+	"hosts.toml": `
+user = "admin"
+
+[[hosts]]
+name = "gateway"
+hostname = "192.168.1.1"
+
+[[groups]]
+name = "prod"
+user = "deploy"
+tags = ["team"]
+note = "Production"
+
+  [[groups.hosts]]
+  name = "web1"
+  hostname = "10.0.0.7"
+  note = "Main web server"
+
+  [[groups.groups]]
+  name = "db"
+  port = 2222
+
+    [[groups.groups.hosts]]
+    hostname = "db1.example.com"
+    tags = ["postgres"]
+`,
+	"hosts.json": `{
+  "user": "admin",
+  "hosts": [{"name": "gateway", "hostname": "192.168.1.1"}],
+  "groups": [{
+    "name": "prod", "user": "deploy", "tags": ["team"], "note": "Production",
+    "hosts": [{"name": "web1", "hostname": "10.0.0.7", "note": "Main web server"}],
+    "groups": [{
+      "name": "db", "port": 2222,
+      "hosts": [{"hostname": "db1.example.com", "tags": ["postgres"]}]
+    }]
+  }]
+}`,
+}
+
+// TestInventorySource tests group inheritance in all inventory formats.
+func TestInventorySource(t *testing.T) {
+	dir := testDir(t, inventoryTests)
+	for name := range inventoryTests {
+		t.Run(name, func(t *testing.T) {
+			checkHosts(t, NewInventorySource(filepath.Join(dir, name), "inventory", 1).Hosts(), []testHost{
+				{"gateway", "ssh://admin@192.168.1.1", nil, ""},
+				{"web1", "ssh://deploy@10.0.0.7", []string{"team", "prod"}, "Main web server"},
+				{"db1.example.com", "ssh://deploy@db1.example.com:2222",
+					[]string{"team", "prod", "db", "postgres"}, "Production"},
+			})
+		})
+	}
+}
+
+// TestInventoryUnknownKeys tests that a misspelt key is an error in
+// every format.
+func TestInventoryUnknownKeys(t *testing.T) {
+	files := map[string]string{
+		"hosts.yaml": "hosts:\n  - hostname: web1\n    usr: bob\n",
+		"hosts.toml": "[[hosts]]\nhostname = \"web1\"\nusr = \"bob\"\n",
+		"hosts.json": `{"hosts": [{"hostname": "web1", "usr": "bob"}]}`,
+	}
+	dir := testDir(t, files)
+	for name := range files {
+		if _, err := readInventoryFile(filepath.Join(dir, name)); err == nil {
+			t.Errorf("[%s] Accepted unknown key", name)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for JSONSource.
+const (
+	DefaultJSONTimeout = 10 * time.Second
+	DefaultJSONMaxAge  = 5 * time.Minute
+)
+
+// JSONFields maps the fields of a JSON document to Host attributes. Each
+// field is a path such as "items[].network.ip": keys are separated by
+// dots, and a key followed by "[]" (or "[]" on its own for the top-level
+// value) is an array whose elements are all visited.
+//
+// Hostname is required. Each element of the innermost array in its path
+// is a host. Paths of other fields are relative to the innermost array
+// they share with Hostname's path, e.g. with Hostname "items[].ips[]",
+// "items[].name" is the name of the item containing each IP address.
+// Other paths apply to all hosts. Tags may match several values, e.g.
+// "items[].labels[]".
+type JSONFields struct {
+	Hostname    string
+	Name        string
+	Port        string
+	User        string
+	Tags        string
+	Description string
+}
+
+// ParseJSONFields parses a field mapping of the form
+// "hostname=items[].ip,name=items[].name,...".
+func ParseJSONFields(s string) (JSONFields, error) {
+	var f JSONFields
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return f, fmt.Errorf("invalid field mapping: %q", pair)
+		}
+		path := strings.TrimSpace(pair[i+1:])
+		switch strings.ToLower(strings.TrimSpace(pair[:i])) {
+		case "hostname":
+			f.Hostname = path
+		case "name":
+			f.Name = path
+		case "port":
+			f.Port = path
+		case "user", "username":
+			f.User = path
+		case "tags":
+			f.Tags = path
+		case "description":
+			f.Description = path
+		default:
+			return f, fmt.Errorf("unknown field: %q", pair[:i])
+		}
+	}
+	if f.Hostname == "" {
+		return f, errors.New("no hostname field")
+	}
+	return f, nil
+}
+
+// JSONSource implements Source for a JSON document fetched from a URL or
+// read from a local file. Fields determines how its values are mapped
+// to Hosts.
+//
+// Documents fetched from a URL are cached for MaxAge. After that, they
+// are revalidated with their ETag, and if the server can't be reached,
+// the stale document is used instead.
+type JSONSource struct {
+	baseSource
+	URL     string      // http(s) URL or path of a local file
+	Fields  JSONFields  // How to map JSON values to Hosts
+	Headers http.Header // Extra request headers, e.g. Authorization
+	MaxAge  time.Duration
+	Client  *http.Client
+	timeout time.Duration
+}
+
+// NewJSONSource creates a new JSONSource for a URL or file path.
+func NewJSONSource(u string, fields JSONFields, name string, priority int, timeout time.Duration) *JSONSource {
+	s := &JSONSource{
+		URL:     u,
+		Fields:  fields,
+		Headers: http.Header{},
+		MaxAge:  DefaultJSONMaxAge,
+		Client:  http.DefaultClient,
+		timeout: timeout,
+	}
+	s.name = name
+	s.priority = priority
+	if s.timeout == 0 {
+		s.timeout = DefaultJSONTimeout
+	}
+	return s
+}
+
+// Timeout implements TimeoutSource.
+func (s *JSONSource) Timeout() time.Duration { return s.timeout }
+
+// Hosts implements Source.
+func (s *JSONSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.HostsContext(context.Background())
+	}
+	return s.hosts
+}
+
+// HostsContext implements ContextSource.
+func (s *JSONSource) HostsContext(ctx context.Context) ([]Host, error) {
+	if s.hosts != nil {
+		return s.hosts, nil
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if s.isRemote() {
+		data, err = s.load(ctx)
+	} else {
+		data, err = ioutil.ReadFile(s.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := parseJSONHosts(data, s.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON from %q: %v", s.URL, err)
+	}
+	s.hosts = make([]Host, len(hosts))
+	for i, h := range hosts {
+		h.source = s.Name()
+		s.hosts[i] = h
+	}
+	log.Printf("[source/load/json] %d host(s) from '%s'", len(s.hosts), s.Name())
+	return s.hosts, nil
+}
+
+// isRemote returns true if the source's URL is an HTTP URL, not a file.
+func (s *JSONSource) isRemote() bool {
+	return strings.HasPrefix(s.URL, "http://") || strings.HasPrefix(s.URL, "https://")
+}
+
+// jsonCacheEntry is a cached document. The ETag is stored with the
+// body, so it always belongs to it.
+type jsonCacheEntry struct {
+	ETag string `json:"etag"`
+	Body []byte `json:"body"`
+}
+
+// load returns the document from the cache or the server.
+func (s *JSONSource) load(ctx context.Context) ([]byte, error) {
+	var cached jsonCacheEntry
+	data, fresh := s.Cache.LoadData("json", s.URL, s.MaxAge)
+	if data != nil {
+		if err := json.Unmarshal(data, &cached); err != nil {
+			log.Printf("[source/json/%s] invalid cache data: %v", s.Name(), err)
+			cached, fresh = jsonCacheEntry{}, false
+		}
+	}
+	if fresh {
+		return cached.Body, nil
+	}
+
+	body, etag, err := s.fetch(ctx, cached.ETag)
+	if err != nil {
+		if cached.Body == nil {
+			return nil, err
+		}
+		log.Printf("[source/json/%s] using stale data: %v", s.Name(), err)
+		return cached.Body, nil
+	}
+	entry := jsonCacheEntry{ETag: etag, Body: body}
+	if body == nil { // not modified
+		log.Printf("[source/json/%s] document not modified", s.Name())
+		entry.Body = cached.Body
+		if entry.ETag == "" {
+			entry.ETag = cached.ETag
+		}
+	}
+
+	// Also re-stored if unchanged, so it's fresh again
+	if data, err = json.Marshal(entry); err != nil {
+		return nil, err
+	}
+	if err := s.Cache.StoreData("json", s.URL, data); err != nil {
+		log.Printf("[source/json/%s] error caching document: %v", s.Name(), err)
+	}
+	return entry.Body, nil
+}
+
+// fetch retrieves the document from the server. If etag is set, it is
+// sent in an If-None-Match header, and body is nil if the server
+// responds that the document hasn't changed.
+func (s *JSONSource) fetch(ctx context.Context, etag string) (body []byte, newTag string, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range s.Headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err = ioutil.ReadAll(resp.Body)
+		return body, resp.Header.Get("ETag"), err
+	case http.StatusNotModified:
+		return nil, resp.Header.Get("ETag"), nil
+	default:
+		return nil, "", fmt.Errorf("GET %s: %s", s.URL, resp.Status)
+	}
+}
+
+// parseJSONHosts maps the values in a JSON document to Hosts.
+func parseJSONHosts(data []byte, f JSONFields) ([]*BaseHost, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	hostPath := parseJSONPath(f.Hostname)
+	// Hosts are the elements of the innermost array in the hostname's path
+	var prefix jsonPath
+	for i, seg := range hostPath {
+		if seg.iter {
+			prefix = hostPath[:i+1]
+		}
+	}
+
+	// values returns the values of path for the host record, which
+	// contains the host's element of each array in prefix.
+	values := func(record []interface{}, path string) []string {
+		if path == "" {
+			return nil
+		}
+		p := parseJSONPath(path)
+		// Relative to the innermost array shared with prefix
+		var end, depth int
+		for i := 0; i < len(p) && i < len(prefix) && p[i] == prefix[i]; i++ {
+			if p[i].iter {
+				end, depth = i+1, depth+1
+			}
+		}
+		if depth == 0 {
+			return p.strings(doc)
+		}
+		return p[end:].strings(record[depth-1])
+	}
+	first := func(record []interface{}, path string) string {
+		if l := values(record, path); len(l) > 0 {
+			return l[0]
+		}
+		return ""
+	}
+
+	var hosts []*BaseHost
+	for i, record := range prefix.records(doc, nil) {
+		hostname := first(record, f.Hostname)
+		if !IsValidHostname(hostname) {
+			log.Printf("[source/json] invalid hostname for entry #%d: %q", i+1, hostname)
+			continue
+		}
+		name := first(record, f.Name)
+		if name == "" {
+			name = hostname
+		}
+		h := &BaseHost{name: name, hostname: hostname, username: first(record, f.User)}
+		if s := first(record, f.Port); s != "" {
+			port, err := strconv.Atoi(s)
+			if err != nil {
+				log.Printf("[source/json] invalid port for %q: %q", name, s)
+				continue
+			}
+			h.port = port
+		}
+		h.meta.AddTag(values(record, f.Tags)...)
+		h.meta.Description = first(record, f.Description)
+		if net.ParseIP(hostname) != nil {
+			h.meta.AddIP(hostname)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// jsonSegment is a key in a jsonPath. If iter is true, the key's value
+// is an array, and the rest of the path is applied to each element.
+type jsonSegment struct {
+	key  string // Empty for the current value
+	iter bool
+}
+
+// jsonPath is a parsed path expression such as "items[].network.ip".
+type jsonPath []jsonSegment
+
+// parseJSONPath parses a path expression.
+func parseJSONPath(s string) jsonPath {
+	var p jsonPath
+	for _, key := range strings.Split(s, ".") {
+		seg := jsonSegment{key: key}
+		if strings.HasSuffix(key, "[]") {
+			seg = jsonSegment{key: strings.TrimSuffix(key, "[]"), iter: true}
+		}
+		if seg.key == "" && !seg.iter {
+			continue
+		}
+		p = append(p, seg)
+	}
+	return p
+}
+
+// eval returns the values of path p in v. Missing keys are ignored.
+func (p jsonPath) eval(v interface{}) []interface{} {
+	if len(p) == 0 {
+		if v == nil {
+			return nil
+		}
+		return []interface{}{v}
+	}
+	seg := p[0]
+	if seg.key != "" {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[seg.key]
+	}
+	if !seg.iter {
+		return p[1:].eval(v)
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	var values []interface{}
+	for _, elem := range l {
+		values = append(values, p[1:].eval(elem)...)
+	}
+	return values
+}
+
+// records returns the elements of the innermost array in p. Each is
+// returned with the elements of the enclosing arrays that contain it,
+// outermost first, appended to parents.
+func (p jsonPath) records(v interface{}, parents []interface{}) [][]interface{} {
+	if len(p) == 0 {
+		if v == nil {
+			return nil
+		}
+		return [][]interface{}{parents}
+	}
+	seg := p[0]
+	if seg.key != "" {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[seg.key]
+	}
+	if !seg.iter {
+		return p[1:].records(v, parents)
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	var records [][]interface{}
+	for _, elem := range l {
+		record := append(parents[:len(parents):len(parents)], elem)
+		records = append(records, p[1:].records(elem, record)...)
+	}
+	return records
+}
+
+// strings returns the scalar values of path p in v as strings.
+func (p jsonPath) strings(v interface{}) []string {
+	var l []string
+	for _, x := range p.eval(v) {
+		switch x := x.(type) {
+		case string:
+			l = append(l, strings.TrimSpace(x))
+		case float64:
+			l = append(l, strconv.FormatFloat(x, 'f', -1, 64))
+		case bool:
+			l = append(l, strconv.FormatBool(x))
+		}
+	}
+	return l
+}
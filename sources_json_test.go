@@ -0,0 +1,203 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJSONDocument = `{
+	"defaults": {"user": "ops"},
+	"items": [
+		{"name": "web1", "network": {"ip": "10.0.0.7", "ssh_port": 2222}, "labels": [{"key": "prod"}, {"key": "web"}],
+		 "note": "Front end"},
+		{"name": "db1", "network": {"ip": "db1.example.com"}, "labels": []},
+		{"name": "broken", "network": {}},
+		{"network": {"ip": "10.0.0.9"}}
+	]
+}`
+
+func TestParseJSONFields(t *testing.T) {
+	f, err := ParseJSONFields("hostname=items[].network.ip, name=items[].name,user = defaults.user,tags=items[].labels[].key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := JSONFields{Hostname: "items[].network.ip", Name: "items[].name", User: "defaults.user", Tags: "items[].labels[].key"}
+	if f != x {
+		t.Errorf("Expected=%+v, Got=%+v", x, f)
+	}
+
+	for _, s := range []string{"", "name=items[].name", "hostname", "hostname=ip,colour=red"} {
+		if _, err := ParseJSONFields(s); err == nil {
+			t.Errorf("Accepted invalid mapping %q", s)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"a": []interface{}{
+			map[string]interface{}{"b": "one", "c": []interface{}{1.0, 2.5}},
+			map[string]interface{}{"b": true},
+			"not an object",
+		},
+	}
+	tests := []struct {
+		path string
+		x    []string
+	}{
+		{"a[].b", []string{"one", "true"}},
+		{"a[].c[]", []string{"1", "2.5"}},
+		{"a[].missing", nil},
+		{"a.b", nil},
+		{"a[]", []string{"not an object"}},
+	}
+	for _, td := range tests {
+		v := parseJSONPath(td.path).strings(doc)
+		if !reflect.DeepEqual(v, td.x) {
+			t.Errorf("Bad values for %q. Expected=%v, Got=%v", td.path, td.x, v)
+		}
+	}
+
+	// Top-level arrays
+	if v := parseJSONPath("[].ip").strings([]interface{}{map[string]interface{}{"ip": "10.0.0.1"}}); !reflect.DeepEqual(v, []string{"10.0.0.1"}) {
+		t.Errorf("Bad top-level array values: %v", v)
+	}
+}
+
+// TestJSONNestedArrays tests paths relative to an enclosing array of
+// the hostname's path.
+func TestJSONNestedArrays(t *testing.T) {
+	doc := `{"site": "lon1", "items": [
+		{"name": "web1", "ips": ["10.0.0.1", "10.0.0.2"]},
+		{"name": "web2", "ips": ["10.0.0.3"]}
+	]}`
+	hosts, err := parseJSONHosts([]byte(doc), JSONFields{Hostname: "items[].ips[]", Name: "items[].name", Tags: "site"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v []string
+	for _, h := range hosts {
+		v = append(v, h.Name()+"="+h.Hostname()+"/"+strings.Join(h.Meta().Tags, ","))
+	}
+	x := []string{"web1=10.0.0.1/lon1", "web1=10.0.0.2/lon1", "web2=10.0.0.3/lon1"}
+	if !reflect.DeepEqual(v, x) {
+		t.Errorf("Expected=%v, Got=%v", x, v)
+	}
+}
+
+// TestJSONSource tests mapping a JSON document fetched via HTTP to Hosts.
+func TestJSONSource(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"hosts.json": `[{"host": "10.1.1.1"}, {"host": "10.1.1.2"}]`,
+	})
+
+	var (
+		requests, notModified int
+		etag                  = `"v1"`
+		ifNoneMatch           string
+	)
+	ts := testServer(t, "Authorization", "Bearer t0ken", "", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ifNoneMatch = r.Header.Get("If-None-Match")
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		if etag != "" && ifNoneMatch == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, testJSONDocument)
+	})
+
+	fields := JSONFields{
+		Hostname:    "items[].network.ip",
+		Name:        "items[].name",
+		Port:        "items[].network.ssh_port",
+		User:        "defaults.user",
+		Tags:        "items[].labels[].key",
+		Description: "items[].note",
+	}
+	s := NewJSONSource(ts.URL+"/hosts.json", fields, "inventory", 1, time.Second)
+	s.Headers.Set("Authorization", "Bearer t0ken")
+	s.Cache = NewHostCache(filepath.Join(dir, "cache"))
+
+	hosts, err := s.HostsContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries without a hostname are skipped, and names default to it
+	checkHosts(t, hosts, []testHost{
+		{"web1", "ssh://ops@10.0.0.7:2222", []string{"prod", "web"}, "Front end"},
+		{"db1", "ssh://ops@db1.example.com", nil, ""},
+		{"10.0.0.9", "ssh://ops@10.0.0.9", nil, ""},
+	})
+
+	// Document is cached
+	s2 := NewJSONSource(s.URL, fields, "inventory", 1, time.Second)
+	s2.Headers = s.Headers
+	s2.Cache = s.Cache
+	if hosts, err := s2.HostsContext(context.Background()); err != nil || len(hosts) != 3 {
+		t.Errorf("Expected 3 cached hosts, Got=%v (err=%v)", hosts, err)
+	}
+	if requests != 1 {
+		t.Errorf("Cache not used. Expected 1 request, Got=%d", requests)
+	}
+
+	// Stale document is revalidated with its ETag
+	s2.hosts = nil
+	s2.MaxAge = 0
+	if hosts, err := s2.HostsContext(context.Background()); err != nil || len(hosts) != 3 {
+		t.Errorf("Expected 3 revalidated hosts, Got=%v (err=%v)", hosts, err)
+	}
+	if notModified != 1 {
+		t.Errorf("Document not revalidated. Expected 1 304 response, Got=%d", notModified)
+	}
+
+	// ETag is forgotten if a new document doesn't have one
+	etag = ""
+	s2.hosts = nil
+	s2.HostsContext(context.Background())
+	if ifNoneMatch != `"v1"` {
+		t.Errorf("Bad If-None-Match. Expected=%q, Got=%q", `"v1"`, ifNoneMatch)
+	}
+	s2.hosts = nil
+	s2.HostsContext(context.Background())
+	if ifNoneMatch != "" {
+		t.Errorf("Stale ETag sent: %q", ifNoneMatch)
+	}
+
+	// Errors fall back to stale data
+	s2.hosts = nil
+	s2.Headers = http.Header{}
+	if hosts, err := s2.HostsContext(context.Background()); err != nil || len(hosts) != 3 {
+		t.Errorf("Expected 3 stale hosts, Got=%v (err=%v)", hosts, err)
+	}
+
+	// and are returned without a cache
+	s3 := NewJSONSource(s.URL, fields, "inventory", 1, time.Second)
+	if _, err := s3.HostsContext(context.Background()); err == nil {
+		t.Error("Expected error for unauthorised request")
+	}
+
+	// Local files are read directly
+	s4 := NewJSONSource(filepath.Join(dir, "hosts.json"), JSONFields{Hostname: "[].host"}, "file", 1, time.Second)
+	if hosts, err := s4.HostsContext(context.Background()); err != nil || len(hosts) != 2 {
+		t.Errorf("Expected 2 hosts from file, Got=%v (err=%v)", hosts, err)
+	}
+}
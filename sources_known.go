@@ -11,6 +11,7 @@ package ssh
 import (
 	"bufio"
 	"log"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -33,13 +34,14 @@ func NewKnownSource(path, name string, priority int) *KnownSource {
 // Hosts implements Source.
 func (s *KnownSource) Hosts() []Host {
 	if s.hosts == nil {
-		hosts := readKnownHostsFile(s.Filepath)
-		log.Printf("[source/load/known_hosts] %d host(s) in '%s'", len(hosts), s.Name())
-		s.hosts = make([]Host, len(hosts))
-		for i, h := range hosts {
-			h.source = s.Name()
-			s.hosts[i] = Host(h)
-		}
+		s.hosts = s.loadHosts("known_hosts", func() ([]Host, []string) {
+			hosts := readKnownHostsFile(s.Filepath)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, nil
+		})
 	}
 	return s.hosts
 }
@@ -88,7 +90,10 @@ func parseKnownHostsLine(line, path string) []*BaseHost {
 	// Parse the found hostnames to see if any specify a non-default
 	// port. Such entries look like [host.name.here]:NNNN instead of
 	// host.name.only
-	var port int
+	var (
+		port int
+		ips  []string
+	)
 
 	for _, hostname := range hostnames {
 
@@ -117,8 +122,17 @@ func parseKnownHostsLine(line, path string) []*BaseHost {
 			continue
 		}
 
+		if net.ParseIP(hostname) != nil {
+			ips = append(ips, hostname)
+		}
+
 		hosts = append(hosts, &BaseHost{name: hostname, hostname: hostname, port: port})
 	}
 
+	// All entries on a line are the same machine
+	for _, h := range hosts {
+		h.meta.AddIP(ips...)
+	}
+
 	return hosts
 }
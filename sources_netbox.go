@@ -0,0 +1,292 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Defaults for NetBoxSource.
+const (
+	DefaultNetBoxTimeout = 20 * time.Second
+	DefaultNetBoxMaxAge  = 15 * time.Minute
+)
+
+// netBoxObject is a device or virtual machine. It's also the format in
+// which they are cached.
+type netBoxObject struct {
+	Kind        string   `json:"kind"` // "device" or "vm"
+	Name        string   `json:"name"`
+	Address     string   `json:"address"` // Primary IP without prefix length
+	Site        string   `json:"site"`
+	Role        string   `json:"role"`
+	Status      string   `json:"status"`
+	Cluster     string   `json:"cluster"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// netBoxName is a related object, e.g. a site, in an API response.
+type netBoxName struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// netBoxResult is a device or virtual machine in an API response.
+type netBoxResult struct {
+	Name      string `json:"name"`
+	PrimaryIP *struct {
+		Address string `json:"address"`
+	} `json:"primary_ip"`
+	Site       *netBoxName `json:"site"`
+	Role       *netBoxName `json:"role"`
+	DeviceRole *netBoxName `json:"device_role"` // NetBox < 3.6
+	Cluster    *netBoxName `json:"cluster"`
+	Status     *struct {
+		Value string `json:"value"`
+	} `json:"status"`
+	Description string       `json:"description"`
+	Tags        []netBoxName `json:"tags"`
+}
+
+// netBoxPage is a page of results from the NetBox API.
+type netBoxPage struct {
+	Next    string         `json:"next"`
+	Results []netBoxResult `json:"results"`
+}
+
+// NetBoxSource implements Source for the devices and virtual machines in
+// NetBox that have a primary IP address. Hosts connect to that address
+// and are tagged with their site, role and NetBox tags.
+//
+// Sites, Roles and Statuses restrict which objects are listed. They
+// contain slugs (or status values such as "active"), and objects
+// matching any of the values are listed.
+//
+// The objects are cached for MaxAge.
+type NetBoxSource struct {
+	baseSource
+	URL      string   // URL of NetBox, e.g. https://netbox.example.com
+	Token    string   // API token
+	Sites    []string // Only list objects at these sites
+	Roles    []string // Only list objects with these roles
+	Statuses []string // Only list objects with these statuses
+	MaxAge   time.Duration
+	Client   *http.Client
+	timeout  time.Duration
+}
+
+// NewNetBoxSource creates a new NetBoxSource for the NetBox at URL u.
+func NewNetBoxSource(u, token, name string, priority int, timeout time.Duration) *NetBoxSource {
+	s := &NetBoxSource{
+		URL:     strings.TrimSuffix(u, "/"),
+		Token:   token,
+		MaxAge:  DefaultNetBoxMaxAge,
+		Client:  http.DefaultClient,
+		timeout: timeout,
+	}
+	s.name = name
+	s.priority = priority
+	if s.timeout == 0 {
+		s.timeout = DefaultNetBoxTimeout
+	}
+	return s
+}
+
+// Timeout implements TimeoutSource.
+func (s *NetBoxSource) Timeout() time.Duration { return s.timeout }
+
+// Hosts implements Source.
+func (s *NetBoxSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.HostsContext(context.Background())
+	}
+	return s.hosts
+}
+
+// HostsContext implements ContextSource.
+func (s *NetBoxSource) HostsContext(ctx context.Context) ([]Host, error) {
+	if s.hosts != nil {
+		return s.hosts, nil
+	}
+
+	key := s.URL + "?" + s.filters().Encode()
+	data, err := s.loadCachedData("netbox", key, s.MaxAge, func() ([]byte, error) {
+		objects, err := s.fetchObjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(objects)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []netBoxObject
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+	s.hosts = []Host{}
+	for _, o := range objects {
+		if o.Name == "" || !IsValidHostname(o.Address) {
+			log.Printf("[source/netbox/%s] invalid %s %q: %q", s.Name(), o.Kind, o.Name, o.Address)
+			continue
+		}
+		h := &BaseHost{name: o.Name, hostname: o.Address, source: s.Name()}
+		h.meta.AddTag("netbox", o.Site, o.Role, o.Cluster)
+		h.meta.AddTag(o.Tags...)
+		h.meta.Description = o.Description
+		h.meta.Set("kind", o.Kind)
+		h.meta.Set("status", o.Status)
+		if net.ParseIP(o.Address) != nil {
+			h.meta.AddIP(o.Address)
+		}
+		s.hosts = append(s.hosts, h)
+	}
+	log.Printf("[source/load/netbox] %d host(s) from '%s'", len(s.hosts), s.Name())
+	return s.hosts, nil
+}
+
+// filters returns the query parameters for the configured filters.
+func (s *NetBoxSource) filters() url.Values {
+	q := url.Values{}
+	for _, v := range s.Sites {
+		q.Add("site", v)
+	}
+	for _, v := range s.Roles {
+		q.Add("role", v)
+	}
+	for _, v := range s.Statuses {
+		q.Add("status", v)
+	}
+	return q
+}
+
+// fetchObjects retrieves all devices and virtual machines that have a
+// primary IP address and match the filters.
+func (s *NetBoxSource) fetchObjects(ctx context.Context) ([]netBoxObject, error) {
+	var (
+		objects []netBoxObject
+		start   = time.Now()
+	)
+	for _, ep := range []struct{ kind, path string }{
+		{"device", "/api/dcim/devices/"},
+		{"vm", "/api/virtualization/virtual-machines/"},
+	} {
+		q := s.filters()
+		q.Set("has_primary_ip", "true")
+		q.Set("limit", "500")
+		next := s.URL + ep.path + "?" + q.Encode()
+		for next != "" {
+			var page netBoxPage
+			err := s.get(ctx, next, &page)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range page.Results {
+				objects = append(objects, r.object(ep.kind))
+			}
+			if next, err = nextNetBoxPage(next, page.Next); err != nil {
+				return nil, err
+			}
+		}
+	}
+	log.Printf("[source/netbox/%s] fetched %d object(s) in %v", s.Name(), len(objects), time.Since(start))
+	return objects, nil
+}
+
+// nextNetBoxPage returns the URL of the page after current. NetBox's
+// "next" URL may have the wrong scheme or host when NetBox is behind a
+// proxy, so only its query (i.e. offset) is used, and the token is never
+// sent to another server.
+func nextNetBoxPage(current, next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+	u, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	n, err := u.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page URL %q: %v", next, err)
+	}
+	u.RawQuery = n.RawQuery
+	return u.String(), nil
+}
+
+// get fetches URL u from the NetBox API and unmarshals the JSON response
+// into v.
+func (s *NetBoxSource) get(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Detail string `json:"detail"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Detail != "" {
+			return fmt.Errorf("NetBox API: %s: %s", resp.Status, e.Detail)
+		}
+		return fmt.Errorf("NetBox API: %s", resp.Status)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// object converts an API result to a netBoxObject.
+func (r netBoxResult) object(kind string) netBoxObject {
+	o := netBoxObject{Kind: kind, Name: r.Name, Description: r.Description}
+	if r.PrimaryIP != nil {
+		// Addresses include the prefix length, e.g. 10.0.0.5/24
+		o.Address = strings.SplitN(r.PrimaryIP.Address, "/", 2)[0]
+	}
+	if r.Site != nil {
+		o.Site = r.Site.Slug
+	}
+	if r.Role == nil {
+		r.Role = r.DeviceRole
+	}
+	if r.Role != nil {
+		o.Role = r.Role.Slug
+	}
+	if r.Cluster != nil {
+		o.Cluster = r.Cluster.Name
+	}
+	if r.Status != nil {
+		o.Status = r.Status.Value
+	}
+	for _, t := range r.Tags {
+		o.Tags = append(o.Tags, t.Slug)
+	}
+	return o
+}
@@ -0,0 +1,86 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestNetBoxSource tests loading devices and VMs from a fake NetBox.
+func TestNetBoxSource(t *testing.T) {
+	var queries []string
+	ts := testServer(t, "Authorization", "Token 0123abcd", `{"detail": "Invalid token"}`, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		queries = append(queries, r.URL.Path+"?"+q.Encode())
+		switch r.URL.Path + "#" + q.Get("offset") {
+		case "/api/dcim/devices/#":
+			// next URL from behind a proxy, which must not be followed as-is
+			fmt.Fprint(w, `{"count": 3, "next": "http://netbox.internal:8000/api/dcim/devices/?offset=2", "results": [
+				{"id": 1, "name": "core-sw1", "primary_ip": {"id": 7, "address": "10.0.0.1/24"},
+				 "site": {"name": "London 1", "slug": "lon1"}, "role": {"name": "Switch", "slug": "switch"},
+				 "status": {"value": "active", "label": "Active"}, "description": "Core switch",
+				 "tags": [{"name": "Core", "slug": "core"}]},
+				{"id": 2, "name": "pdu1", "primary_ip": {"id": 8, "address": "2001:db8::10/64"},
+				 "site": {"name": "London 1", "slug": "lon1"}, "device_role": {"name": "PDU", "slug": "pdu"},
+				 "status": {"value": "active"}}
+			]}`)
+		case "/api/dcim/devices/#2":
+			fmt.Fprint(w, `{"count": 3, "next": null, "results": [
+				{"id": 3, "name": null, "primary_ip": {"address": "10.0.0.9/24"}}
+			]}`)
+		case "/api/virtualization/virtual-machines/#":
+			fmt.Fprint(w, `{"count": 1, "next": null, "results": [
+				{"id": 10, "name": "app1", "primary_ip": {"address": "10.0.5.20/24"},
+				 "site": {"slug": "lon1"}, "role": {"slug": "app"}, "cluster": {"name": "vmware-a"},
+				 "status": {"value": "active"}, "tags": []}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	s := NewNetBoxSource(ts.URL+"/", "0123abcd", "NetBox", 1, time.Second)
+	s.Sites = []string{"lon1"}
+	s.Statuses = []string{"active", "staged"}
+
+	hosts, err := s.HostsContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHosts(t, hosts, []testHost{
+		{"core-sw1", "ssh://10.0.0.1", []string{"netbox", "lon1", "switch", "core"}, "Core switch"},
+		{"pdu1", "ssh://[2001:db8::10]", []string{"netbox", "lon1", "pdu"}, ""},
+		{"app1", "ssh://10.0.5.20", []string{"netbox", "lon1", "app", "vmware-a"}, ""},
+	})
+	if x := hosts[2].Meta().Get("kind"); x != "vm" {
+		t.Errorf("Bad kind. Expected=vm, Got=%q", x)
+	}
+
+	// Filters are sent
+	x := "/api/dcim/devices/?has_primary_ip=true&limit=500&site=lon1&status=active&status=staged"
+	if len(queries) == 0 || queries[0] != x {
+		t.Errorf("Bad query. Expected=%q, Got=%v", x, queries)
+	}
+	// Next page is fetched from the configured URL
+	if x := "/api/dcim/devices/?offset=2"; len(queries) < 2 || queries[1] != x {
+		t.Errorf("Bad next page. Expected=%q, Got=%v", x, queries)
+	}
+
+	// API errors
+	s2 := NewNetBoxSource(ts.URL, "wrong", "NetBox", 1, time.Second)
+	_, err = s2.HostsContext(context.Background())
+	if x := "NetBox API: 403 Forbidden: Invalid token"; err == nil || err.Error() != x {
+		t.Errorf("Bad error. Expected=%q, Got=%v", x, err)
+	}
+}
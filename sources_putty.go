@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Name of PuTTY's session containing the defaults for new sessions.
+const puttyDefaultSession = "Default Settings"
+
+// PuTTYSource implements Source for a directory of PuTTY session files,
+// i.e. ~/.putty/sessions. Each file is a saved session containing
+// Key=Value lines, and its name is the URL-encoded name of the session.
+// Only SSH sessions are read.
+type PuTTYSource struct {
+	baseSource
+}
+
+// NewPuTTYSource creates a new PuTTYSource for a sessions directory.
+func NewPuTTYSource(path, name string, priority int) *PuTTYSource {
+	s := &PuTTYSource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *PuTTYSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("putty", func() ([]Host, []string) {
+			hosts, files := readPuTTYSessions(s.Filepath)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, files
+		})
+	}
+	return s.hosts
+}
+
+// readPuTTYSessions reads hosts from the session files in dir. It also
+// returns the paths of the files.
+func readPuTTYSessions(dir string) ([]*BaseHost, []string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Printf("[putty/%s] %v", dir, err)
+		return nil, nil
+	}
+
+	var (
+		hosts []*BaseHost
+		files []string
+	)
+	for _, fi := range infos {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		name, err := url.PathUnescape(fi.Name())
+		if err != nil {
+			name = fi.Name()
+		}
+		if name == puttyDefaultSession {
+			continue
+		}
+
+		path := filepath.Join(dir, fi.Name())
+		files = append(files, path)
+		h, err := readPuTTYSession(path)
+		if err != nil {
+			log.Printf("[putty/%s] %v", path, err)
+			continue
+		}
+		if h == nil {
+			continue
+		}
+		h.name = name
+		hosts = append(hosts, h)
+	}
+	return hosts, files
+}
+
+// readPuTTYSession reads a session file. It returns nil if the session
+// isn't an SSH session or has no hostname.
+func readPuTTYSession(path string) (*BaseHost, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var (
+		h        = &BaseHost{}
+		protocol = "ssh"
+		scanner  = bufio.NewScanner(fp)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.Index(line, "=")
+		if i < 1 {
+			continue
+		}
+		key, value := line[:i], strings.TrimSpace(line[i+1:])
+		switch key {
+		case "HostName":
+			h.hostname = value
+		case "PortNumber":
+			if port, err := strconv.Atoi(value); err == nil {
+				h.port = port
+			}
+		case "UserName":
+			h.username = value
+		case "Protocol":
+			protocol = strings.ToLower(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if protocol != "ssh" || h.hostname == "" {
+		return nil, nil
+	}
+	// HostName may be user@host
+	if i := strings.LastIndex(h.hostname, "@"); i > -1 {
+		if h.username == "" {
+			h.username = h.hostname[:i]
+		}
+		h.hostname = h.hostname[i+1:]
+	}
+	if !IsValidHostname(h.hostname) {
+		log.Printf("[putty] invalid hostname in %s: %q", path, h.hostname)
+		return nil, nil
+	}
+	if net.ParseIP(h.hostname) != nil {
+		h.meta.AddIP(h.hostname)
+	}
+	h.meta.AddTag("putty")
+	return h, nil
+}
@@ -0,0 +1,30 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"testing"
+)
+
+var puttySessions = map[string]string{
+	"Default%20Settings":  "HostName=\nPortNumber=22\nProtocol=ssh\n",
+	"Web%20Server%20%231": "HostName=10.0.0.7\nPortNumber=2222\nUserName=deploy\nProtocol=ssh\n",
+	"db":                  "Present=1\nHostName=admin@db.example.com\nPortNumber=22\nProtocol=ssh\n",
+	"router":              "HostName=192.168.1.1\nPortNumber=23\nProtocol=telnet\n",
+	"serial":              "HostName=\nProtocol=serial\n",
+}
+
+// TestPuTTYSource tests reading PuTTY session files.
+func TestPuTTYSource(t *testing.T) {
+	// Session names are unescaped, and only SSH sessions are included
+	checkHosts(t, NewPuTTYSource(testDir(t, puttySessions), "putty", 1).Hosts(), []testHost{
+		{"Web Server #1", "ssh://deploy@10.0.0.7:2222", []string{"putty"}, ""},
+		{"db", "ssh://admin@db.example.com", []string{"putty"}, ""},
+	})
+}
@@ -0,0 +1,519 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Options of ssh, scp and sftp that take an argument.
+var (
+	sshArgOptions  = "BbcDEeFIiJLlmOoPpQRSWw"
+	scpArgOptions  = "cDFiJlOoPSX"
+	sftpArgOptions = "BbcDFiJlOoPRSsX"
+)
+
+// Long options of mosh that take an argument (unless given as --opt=arg).
+var moshArgOptions = map[string]bool{
+	"--client": true, "--server": true, "--ssh": true, "--predict": true,
+	"--family": true, "--port": true, "-p": true, "--bind-server": true,
+	"--experimental-remote-ip": true,
+}
+
+// Commands that may precede the program in a command line.
+var shellPrefixCommands = map[string]bool{
+	"sudo": true, "exec": true, "command": true, "time": true,
+	"nohup": true, "noglob": true, "env": true,
+}
+
+// zsh extended history line, e.g. ": 1700000000:0;ssh host".
+var zshExtendedLine = regexp.MustCompile(`^: \d+:\d+;`)
+
+// ShellHistorySource implements Source for a shell history file. It finds
+// the hosts connected to with ssh, mosh, scp and sftp. bash, zsh (plain
+// and extended format) and fish histories are supported; the format is
+// detected from the file's contents.
+//
+// Hosts are listed most recently used first.
+type ShellHistorySource struct {
+	baseSource
+}
+
+// NewShellHistorySource creates a new ShellHistorySource for a history file.
+func NewShellHistorySource(path, name string, priority int) *ShellHistorySource {
+	s := &ShellHistorySource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *ShellHistorySource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("shell", func() ([]Host, []string) {
+			hosts := readShellHistory(s.Filepath)
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, nil
+		})
+	}
+	return s.hosts
+}
+
+// readShellHistory reads hosts from a shell history file.
+func readShellHistory(path string) []*BaseHost {
+	fp, err := os.Open(path)
+	if err != nil {
+		log.Printf("[shell/%s] Error opening file: %v", path, err)
+		return nil
+	}
+	defer fp.Close()
+
+	var (
+		hosts []*BaseHost
+		seen  = map[string]int{} // user@host:port -> index in hosts
+	)
+	scanner := bufio.NewScanner(fp)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := historyCommand(unmetafy(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		for _, h := range parseShellCommand(line) {
+			// Move hosts used again to the end, so they're first when
+			// the list is reversed.
+			key := h.username + "@" + HostPort(h.hostname, h.port)
+			if i, ok := seen[key]; ok {
+				hosts[i] = nil
+			}
+			seen[key] = len(hosts)
+			hosts = append(hosts, h)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("[shell/%s] Error reading file: %v", path, err)
+	}
+
+	// Most recent first
+	var l []*BaseHost
+	for i := len(hosts) - 1; i >= 0; i-- {
+		if hosts[i] != nil {
+			l = append(l, hosts[i])
+		}
+	}
+	return l
+}
+
+// historyCommand returns the command in a line of a history file, or
+// an empty string if the line doesn't contain one (e.g. a timestamp).
+func historyCommand(line string) string {
+	switch {
+	case strings.HasPrefix(line, "- cmd: "): // fish
+		s := strings.TrimPrefix(line, "- cmd: ")
+		return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(s)
+	case strings.HasPrefix(line, "  when: "), strings.HasPrefix(line, "  paths:"),
+		strings.HasPrefix(line, "    - "): // fish metadata
+		return ""
+	case zshExtendedLine.MatchString(line):
+		return line[strings.Index(line, ";")+1:]
+	case strings.HasPrefix(line, "#"): // bash timestamp or comment
+		return ""
+	}
+	return line
+}
+
+// unmetafy decodes zsh's "metafied" history, in which some bytes are
+// written as 0x83 followed by the byte XOR 32.
+func unmetafy(s string) string {
+	if strings.IndexByte(s, 0x83) < 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x83 && i+1 < len(s) {
+			i++
+			b = append(b, s[i]^32)
+			continue
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// parseShellCommand returns the hosts connected to by the ssh, mosh,
+// scp and sftp commands in a command line.
+func parseShellCommand(line string) []*BaseHost {
+	var hosts []*BaseHost
+	for _, words := range splitShellCommands(line) {
+		// Skip variable assignments and prefixes like sudo
+		for len(words) > 0 && (shellPrefixCommands[words[0]] || isShellAssignment(words[0])) {
+			words = words[1:]
+		}
+		if len(words) < 2 {
+			continue
+		}
+
+		var h *BaseHost
+		switch prog := filepath.Base(words[0]); prog {
+		case "ssh":
+			h = parseSSHArgs(words[1:])
+		case "mosh":
+			h = parseMoshArgs(words[1:])
+		case "scp":
+			hosts = append(hosts, parseSCPArgs(words[1:])...)
+		case "sftp":
+			h = parseSFTPArgs(words[1:])
+		}
+		if h != nil {
+			hosts = append(hosts, h)
+		}
+	}
+
+	var valid []*BaseHost
+	for _, h := range hosts {
+		if IsValidHostname(h.hostname) {
+			// Same form as the queries saved in History
+			h.name = userHost(h.username, HostPort(h.hostname, h.port))
+			if net.ParseIP(h.hostname) != nil {
+				h.meta.AddIP(h.hostname)
+			}
+			valid = append(valid, h)
+		}
+	}
+	return valid
+}
+
+// isShellAssignment returns true if s is a variable assignment, e.g.
+// TERM=xterm.
+func isShellAssignment(s string) bool {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return false
+	}
+	for _, r := range s[:i] {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitShellCommands splits a command line into words, honouring quotes
+// and backslashes, and into separate commands at ;, &, | and newlines.
+// It's not a real shell parser, but good enough for ssh command lines.
+func splitShellCommands(line string) [][]string {
+	var (
+		commands [][]string
+		words    []string
+		word     []rune
+		inWord   bool
+		quote    rune
+		escaped  bool
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, string(word))
+		}
+		word, inWord = word[:0], false
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+		}
+		words = nil
+	}
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word, inWord, escaped = append(word, r), true, false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			} else {
+				word = append(word, r)
+			}
+		case r == '\\':
+			escaped = true
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			endWord()
+		case r == ';' || r == '&' || r == '|' || r == '\n' || r == '(' || r == ')':
+			endCommand()
+		case r == '#' && !inWord:
+			endCommand()
+			return commands
+		default:
+			word, inWord = append(word, r), true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// parseSSHArgs returns the host in the arguments of ssh.
+func parseSSHArgs(args []string) *BaseHost {
+	h := &BaseHost{}
+	dest, opts := parseShortOptions(args, sshArgOptions)
+	if dest == "" {
+		return nil
+	}
+	if strings.HasPrefix(dest, "ssh://") {
+		if !parseDestURL(h, dest) {
+			return nil
+		}
+	} else {
+		h.username, h.hostname, h.port = ParseQuery(dest)
+		// ssh doesn't accept host:port
+		if h.port != 0 && !strings.HasPrefix(dest, "[") {
+			return nil
+		}
+	}
+	applySSHOptions(h, opts, 'p')
+	return h
+}
+
+// parseSFTPArgs returns the host in the arguments of sftp.
+func parseSFTPArgs(args []string) *BaseHost {
+	h := &BaseHost{}
+	dest, opts := parseShortOptions(args, sftpArgOptions)
+	if dest == "" {
+		return nil
+	}
+	if strings.HasPrefix(dest, "sftp://") {
+		if !parseDestURL(h, dest) {
+			return nil
+		}
+	} else {
+		// [user@]host[:path]
+		host, _ := splitSCPTarget(dest)
+		if host == "" {
+			host = dest
+		}
+		h.username, h.hostname, _ = ParseQuery(host)
+	}
+	applySSHOptions(h, opts, 'P')
+	return h
+}
+
+// parseSCPArgs returns the hosts in the arguments of scp.
+func parseSCPArgs(args []string) []*BaseHost {
+	var (
+		hosts       []*BaseHost
+		opts        = map[byte][]string{}
+		afterDashes bool
+	)
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !afterDashes && a == "--" {
+			afterDashes = true
+			continue
+		}
+		if !afterDashes && len(a) > 1 && a[0] == '-' {
+			i += parseShortOption(a, args[i+1:], scpArgOptions, opts)
+			continue
+		}
+
+		h := &BaseHost{}
+		if strings.HasPrefix(a, "scp://") {
+			if !parseDestURL(h, a) {
+				continue
+			}
+		} else {
+			host, ok := splitSCPTarget(a)
+			if !ok {
+				continue // local file
+			}
+			h.username, h.hostname, _ = ParseQuery(host)
+		}
+		hosts = append(hosts, h)
+	}
+	for _, h := range hosts {
+		applySSHOptions(h, opts, 'P')
+	}
+	return hosts
+}
+
+// parseMoshArgs returns the host in the arguments of mosh. mosh's
+// --port option is its UDP port, so the SSH port is read from --ssh.
+func parseMoshArgs(args []string) *BaseHost {
+	var (
+		h    = &BaseHost{}
+		dest string
+	)
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			if i+1 < len(args) {
+				dest = args[i+1]
+			}
+			break
+		}
+		if strings.HasPrefix(a, "-") {
+			value := ""
+			if j := strings.Index(a, "="); j > 0 {
+				a, value = a[:j], a[j+1:]
+			} else if moshArgOptions[a] && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			if a == "--ssh" {
+				// e.g. --ssh="ssh -p 2222"
+				if words := splitShellCommands(value); len(words) > 0 && len(words[0]) > 1 {
+					_, opts := parseShortOptions(words[0][1:], sshArgOptions)
+					applySSHOptions(h, opts, 'p')
+				}
+			}
+			continue
+		}
+		dest = a
+		break
+	}
+	if dest == "" {
+		return nil
+	}
+	port := h.port
+	h.username, h.hostname, _ = ParseQuery(dest)
+	h.port = port
+	return h
+}
+
+// parseShortOptions parses the options of ssh or sftp up to the
+// destination, which it returns. argOptions are the options that take
+// an argument.
+func parseShortOptions(args []string, argOptions string) (dest string, opts map[byte][]string) {
+	opts = map[byte][]string{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			if i+1 < len(args) {
+				dest = args[i+1]
+			}
+			return
+		}
+		if len(a) > 1 && a[0] == '-' {
+			i += parseShortOption(a, args[i+1:], argOptions, opts)
+			continue
+		}
+		return a, opts
+	}
+	return "", opts
+}
+
+// parseShortOption parses a group of short options, e.g. "-vp" or
+// "-p2222", and adds their values to opts. It returns the number of
+// following arguments consumed.
+func parseShortOption(a string, rest []string, argOptions string, opts map[byte][]string) int {
+	for j := 1; j < len(a); j++ {
+		c := a[j]
+		if strings.IndexByte(argOptions, c) < 0 {
+			continue
+		}
+		if j+1 < len(a) { // -p2222
+			opts[c] = append(opts[c], a[j+1:])
+			return 0
+		}
+		if len(rest) > 0 { // -p 2222
+			opts[c] = append(opts[c], rest[0])
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
+
+// applySSHOptions sets the port, username, jump host and identity file
+// of h from command-line options. portOption is -p for ssh and -P for
+// scp and sftp. Explicit options override the destination, as in ssh.
+func applySSHOptions(h *BaseHost, opts map[byte][]string, portOption byte) {
+	for _, o := range opts['o'] {
+		// -o Port=2222 or -o "Port 2222"
+		i := strings.IndexAny(o, "= ")
+		if i < 1 {
+			continue
+		}
+		key, value := strings.ToLower(o[:i]), strings.TrimSpace(o[i+1:])
+		switch key {
+		case "port":
+			opts[portOption] = append(opts[portOption], value)
+		case "user":
+			h.username = value
+		case "identityfile":
+			opts['i'] = append(opts['i'], value)
+		case "proxyjump":
+			opts['J'] = append(opts['J'], value)
+		}
+	}
+	if l := opts[portOption]; len(l) > 0 {
+		if port, err := strconv.Atoi(l[0]); err == nil {
+			h.port = port
+		}
+	}
+	// -l is the bandwidth limit for scp and sftp
+	if l := opts['l']; len(l) > 0 && portOption == 'p' {
+		h.username = l[0]
+	}
+	if l := opts['i']; len(l) > 0 {
+		h.meta.IdentityFile = l[0]
+	}
+	if l := opts['J']; len(l) > 0 && l[0] != "none" {
+		h.meta.JumpHost = l[0]
+	}
+}
+
+// parseDestURL sets the user, host and port of h from an ssh://, sftp://
+// or scp:// URL.
+func parseDestURL(h *BaseHost, s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	h.hostname = u.Hostname()
+	if u.User != nil {
+		// Strip fingerprint, e.g. ssh://user;fingerprint=...@host
+		h.username = strings.SplitN(u.User.Username(), ";", 2)[0]
+	}
+	if p, err := strconv.Atoi(u.Port()); err == nil {
+		h.port = p
+	}
+	return true
+}
+
+// splitSCPTarget returns the [user@]host part of an scp-style remote path,
+// i.e. [user@]host:path. ok is false if s is a local path.
+func splitSCPTarget(s string) (host string, ok bool) {
+	if strings.HasPrefix(s, "[") { // [IPv6]:path
+		i := strings.Index(s, "]:")
+		if i < 0 {
+			return "", false
+		}
+		return s[:i+1], true
+	}
+	i := strings.Index(s, ":")
+	if i < 1 || strings.Contains(s[:i], "/") {
+		return "", false
+	}
+	return s[:i], true
+}
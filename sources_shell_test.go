@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseShellCommand(t *testing.T) {
+	type tHost struct {
+		User, Hostname string
+		Port           int
+	}
+	tests := []struct {
+		in  string
+		out []tHost
+	}{
+		{"ls -l", nil},
+		{"ssh", nil},
+		{"ssh host", []tHost{{"", "host", 22}}},
+		{"ssh -p 2200 ops@foo", []tHost{{"ops", "foo", 2200}}},
+		{"ssh -vp2200 -l ops foo uptime", []tHost{{"ops", "foo", 2200}}},
+		{"ssh -i ~/.ssh/id -o Port=2201 -o 'User bob' foo", []tHost{{"bob", "foo", 2201}}},
+		{"ssh ssh://bob@[2001:db8::5]:2222", []tHost{{"bob", "2001:db8::5", 2222}}},
+		{"ssh -- -oProxyCommand=x", nil},
+		{"ssh host:2222", nil},
+		{"ssh $HOST", nil},
+		{"TERM=xterm sudo /usr/bin/ssh -A a.example.com", []tHost{{"", "a.example.com", 22}}},
+		{"cd /tmp && ssh a; ssh b | tee log # ssh c", []tHost{{"", "a", 22}, {"", "b", 22}}},
+		{`mosh --ssh="ssh -p 2222" -p 60001 bob@moshhost`, []tHost{{"bob", "moshhost", 2222}}},
+		{"mosh --port=60001 moshhost -- tmux attach", []tHost{{"", "moshhost", 22}}},
+		{"scp -P 2222 -l 100 file.txt bob@dest:/tmp/ ./local:file", []tHost{{"bob", "dest", 2222}}},
+		{"scp src:a.txt scp://bob@dest:2200/b.txt", []tHost{{"", "src", 22}, {"bob", "dest", 2200}}},
+		{"sftp -P 2200 ops@files:/srv", []tHost{{"ops", "files", 2200}}},
+		{"sftp sftp://ops@files:2200/srv", []tHost{{"ops", "files", 2200}}},
+	}
+
+	for _, td := range tests {
+		var v []tHost
+		for _, h := range parseShellCommand(td.in) {
+			v = append(v, tHost{h.Username(), h.Hostname(), h.Port()})
+		}
+		if !reflect.DeepEqual(v, td.out) {
+			t.Errorf("Bad hosts for %q. Expected=%v, Got=%v", td.in, td.out, v)
+		}
+	}
+
+	// Names include user and port
+	var names []string
+	for _, h := range parseShellCommand("ssh root@db; ssh -p 2200 deploy@db; ssh db; ssh -p 2222 2001:db8::5") {
+		names = append(names, h.Name())
+	}
+	if x := []string{"root@db", "deploy@db:2200", "db", "[2001:db8::5]:2222"}; !reflect.DeepEqual(names, x) {
+		t.Errorf("Bad names. Expected=%v, Got=%v", x, names)
+	}
+}
+
+var shellHistoryTests = map[string]string{
+	"bash_history": "#1700000000\nssh old\nls\n#1700000001\nssh -p 2200 ops@foo\nssh old\n",
+	"zsh_history":  ": 1700000000:0;ssh old\n: 1700000001:0;ssh -p 2200 ops@foo\n: 1700000002:0;ssh old\n",
+	"fish_history": "- cmd: ssh old\n  when: 1700000000\n- cmd: ssh -p 2200 ops@foo\n  when: 1700000001\n" +
+		"  paths:\n    - ssh\n- cmd: ssh old\n  when: 1700000002\n",
+}
+
+// TestShellHistorySource tests reading different history formats.
+func TestShellHistorySource(t *testing.T) {
+	dir := testDir(t, shellHistoryTests)
+	for name := range shellHistoryTests {
+		t.Run(name, func(t *testing.T) {
+			// Most recent first
+			checkHosts(t, NewShellHistorySource(filepath.Join(dir, name), name, 1).Hosts(), []testHost{
+				{"old", "ssh://old", nil, ""},
+				{"ops@foo:2200", "ssh://ops@foo:2200", nil, ""},
+			})
+		})
+	}
+}
+
+func TestUnmetafy(t *testing.T) {
+	// "œ" is 0xc5 0x93; zsh writes 0x93 as 0x83 0xb3
+	if s := unmetafy("ssh c\xc5\x83\xb3ur"); s != "ssh cœur" {
+		t.Errorf("Bad unmetafy. Expected=%q, Got=%q", "ssh cœur", s)
+	}
+}
@@ -0,0 +1,242 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// terraformAddressAttrs are the attributes of the Terraform resource types
+// that contain a host's addresses, in order of preference. Nested
+// attributes are separated by dots, e.g. "network_interface.0.network_ip".
+var terraformAddressAttrs = map[string][]string{
+	"aws_instance":                    {"public_ip", "public_dns", "private_ip", "private_dns"},
+	"aws_eip":                         {"public_ip", "public_dns", "private_ip", "private_dns"},
+	"aws_lightsail_instance":          {"public_ip_address", "private_ip_address"},
+	"azurerm_linux_virtual_machine":   {"public_ip_address", "private_ip_address"},
+	"azurerm_windows_virtual_machine": {"public_ip_address", "private_ip_address"},
+	"azurerm_public_ip":               {"fqdn", "ip_address"},
+	"digitalocean_droplet":            {"ipv4_address", "ipv4_address_private", "ipv6_address"},
+	"google_compute_instance":         {"network_interface.0.access_config.0.nat_ip", "network_interface.0.network_ip"},
+	"hcloud_server":                   {"ipv4_address", "ipv6_address"},
+	"libvirt_domain":                  {"network_interface.0.addresses.0"},
+	"linode_instance":                 {"ip_address", "private_ip_address"},
+	"openstack_compute_instance_v2":   {"access_ip_v4", "access_ip_v6"},
+	"vsphere_virtual_machine":         {"default_ip_address"},
+	"vultr_instance":                  {"main_ip", "internal_ip"},
+	"oci_core_instance":               {"public_ip", "private_ip"},
+	"proxmox_vm_qemu":                 {"ssh_host", "default_ipv4_address"},
+}
+
+// terraformState is the subset of a Terraform state file (format version
+// 4, Terraform 0.12+) needed to find hosts.
+type terraformState struct {
+	Version   int                 `json:"version"`
+	Resources []terraformResource `json:"resources"`
+	Modules   []terraformModule   `json:"modules"` // Version 3
+}
+
+type terraformResource struct {
+	Module    string              `json:"module"`
+	Mode      string              `json:"mode"`
+	Type      string              `json:"type"`
+	Name      string              `json:"name"`
+	Instances []terraformInstance `json:"instances"`
+}
+
+type terraformInstance struct {
+	IndexKey   interface{}            `json:"index_key"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// terraformModule is a module in a version 3 (Terraform <0.12) state file,
+// whose resources are keyed by address and have flat string attributes.
+type terraformModule struct {
+	Path      []string `json:"path"`
+	Resources map[string]struct {
+		Type    string `json:"type"`
+		Primary struct {
+			Attributes map[string]string `json:"attributes"`
+		} `json:"primary"`
+	} `json:"resources"`
+}
+
+// TerraformSource implements Source for a local Terraform state file
+// (terraform.tfstate). Hosts are created for the instances of resource
+// types that have IP addresses or DNS names (see terraformAddressAttrs)
+// and are named after the resource's address, e.g. "aws_instance.web[0]".
+type TerraformSource struct {
+	baseSource
+}
+
+// NewTerraformSource creates a new TerraformSource for a state file.
+func NewTerraformSource(path, name string, priority int) *TerraformSource {
+	s := &TerraformSource{}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Hosts implements Source.
+func (s *TerraformSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.hosts = s.loadHosts("terraform", func() ([]Host, []string) {
+			hosts, err := readTerraformState(s.Filepath)
+			if err != nil {
+				log.Printf("[terraform/%s] %v", s.Filepath, err)
+			}
+			l := make([]Host, len(hosts))
+			for i, h := range hosts {
+				l[i] = h
+			}
+			return l, nil
+		})
+	}
+	return s.hosts
+}
+
+// readTerraformState reads hosts from a Terraform state file. Hosts are
+// tagged with their resource type, module and the name of the directory
+// the state file is in (i.e. the project).
+func readTerraformState(path string) ([]*BaseHost, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st terraformState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+
+	var (
+		hosts   []*BaseHost
+		project = filepath.Base(filepath.Dir(path))
+	)
+	add := func(address, typ, module string, attr func(string) string) {
+		attrs, ok := terraformAddressAttrs[typ]
+		if !ok {
+			return
+		}
+		h := &BaseHost{name: address}
+		for _, key := range attrs {
+			v := attr(key)
+			if v == "" || !IsValidHostname(v) {
+				continue
+			}
+			if h.hostname == "" {
+				h.hostname = v
+			}
+			if net.ParseIP(v) != nil {
+				h.meta.AddIP(v)
+			}
+		}
+		if h.hostname == "" {
+			log.Printf("[terraform] no address for %s", address)
+			return
+		}
+		h.meta.AddTag(typ, module, project)
+		hosts = append(hosts, h)
+	}
+
+	if st.Version < 4 {
+		for _, m := range st.Modules {
+			path := m.Path // First element is "root"
+			if len(path) > 0 {
+				path = path[1:]
+			}
+			module := strings.Join(path, ".")
+			prefix := ""
+			for _, p := range path {
+				prefix += "module." + p + "."
+			}
+			keys := make([]string, 0, len(m.Resources))
+			for k := range m.Resources {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				r := m.Resources[k]
+				if strings.HasPrefix(k, "data.") {
+					continue
+				}
+				attrs := r.Primary.Attributes
+				add(prefix+terraformV3Address(k), r.Type, module, func(key string) string { return attrs[key] })
+			}
+		}
+		return hosts, nil
+	}
+
+	for _, r := range st.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		address := r.Type + "." + r.Name
+		if r.Module != "" {
+			address = r.Module + "." + address
+		}
+		module := strings.TrimPrefix(r.Module, "module.")
+		for _, inst := range r.Instances {
+			name := address
+			switch k := inst.IndexKey.(type) {
+			case float64:
+				name = fmt.Sprintf("%s[%d]", address, int(k))
+			case string:
+				name = fmt.Sprintf("%s[%q]", address, k)
+			}
+			attrs := inst.Attributes
+			add(name, r.Type, module, func(key string) string { return terraformAttr(attrs, key) })
+		}
+	}
+	return hosts, nil
+}
+
+// terraformV3Address converts a version 3 resource key, e.g.
+// "aws_instance.web.1", to an address, e.g. "aws_instance.web[1]".
+func terraformV3Address(key string) string {
+	parts := strings.Split(key, ".")
+	if len(parts) == 3 {
+		if _, err := strconv.Atoi(parts[2]); err == nil {
+			return fmt.Sprintf("%s.%s[%s]", parts[0], parts[1], parts[2])
+		}
+	}
+	return key
+}
+
+// terraformAttr returns the value of a (nested) attribute as a string.
+// key is a dot-separated path of object keys and list indices.
+func terraformAttr(attrs map[string]interface{}, key string) string {
+	var v interface{} = attrs
+	for _, k := range strings.Split(key, ".") {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			v = x[k]
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i >= len(x) {
+				return ""
+			}
+			v = x[i]
+		default:
+			return ""
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
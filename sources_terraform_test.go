@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var terraformTests = map[string]string{
+	"v4": `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "data", "type": "aws_instance", "name": "existing",
+      "instances": [{"attributes": {"public_ip": "1.1.1.1"}}]
+    },
+    {
+      "mode": "managed", "type": "aws_security_group", "name": "sg",
+      "instances": [{"attributes": {"name": "sg"}}]
+    },
+    {
+      "mode": "managed", "type": "aws_instance", "name": "web",
+      "instances": [
+        {"index_key": 0, "attributes": {"public_ip": "54.1.2.3", "private_ip": "10.0.0.5",
+          "public_dns": "ec2-54-1-2-3.compute.amazonaws.com"}},
+        {"index_key": 1, "attributes": {"public_ip": "", "private_ip": "10.0.0.6"}}
+      ]
+    },
+    {
+      "module": "module.db", "mode": "managed", "type": "google_compute_instance", "name": "pg",
+      "instances": [
+        {"index_key": "main", "attributes": {"network_interface": [
+          {"network_ip": "10.1.0.2", "access_config": [{"nat_ip": "35.1.2.3"}]}
+        ]}}
+      ]
+    }
+  ]
+}`,
+	"v3": `{
+  "version": 3,
+  "modules": [
+    {
+      "path": ["root"],
+      "resources": {
+        "aws_instance.web.0": {"type": "aws_instance", "primary": {"attributes": {
+          "public_ip": "54.1.2.3", "private_ip": "10.0.0.5",
+          "public_dns": "ec2-54-1-2-3.compute.amazonaws.com"}}},
+        "aws_instance.web.1": {"type": "aws_instance", "primary": {"attributes": {
+          "public_ip": "", "private_ip": "10.0.0.6"}}},
+        "data.aws_instance.existing": {"type": "aws_instance", "primary": {"attributes": {
+          "public_ip": "1.1.1.1"}}}
+      }
+    },
+    {
+      "path": ["root", "db"],
+      "resources": {
+        "google_compute_instance.pg": {"type": "google_compute_instance", "primary": {"attributes": {
+          "network_interface.0.network_ip": "10.1.0.2",
+          "network_interface.0.access_config.0.nat_ip": "35.1.2.3"}}}
+      }
+    }
+  ]
+}`,
+}
+
+// TestTerraformSource tests reading hosts from version 3 and 4 state files.
+func TestTerraformSource(t *testing.T) {
+	files := map[string]string{}
+	for name, data := range terraformTests {
+		files[filepath.Join(name, "terraform.tfstate")] = data
+	}
+	dir := testDir(t, files)
+
+	// Public addresses are preferred, and v3 state has no instance keys
+	expected := map[string][]testHost{
+		"v4": {
+			{"aws_instance.web[0]", "ssh://54.1.2.3", []string{"aws_instance", "v4"}, ""},
+			{"aws_instance.web[1]", "ssh://10.0.0.6", []string{"aws_instance", "v4"}, ""},
+			{`module.db.google_compute_instance.pg["main"]`, "ssh://35.1.2.3",
+				[]string{"google_compute_instance", "db", "v4"}, ""},
+		},
+		"v3": {
+			{"aws_instance.web[0]", "ssh://54.1.2.3", []string{"aws_instance", "v3"}, ""},
+			{"aws_instance.web[1]", "ssh://10.0.0.6", []string{"aws_instance", "v3"}, ""},
+			{"module.db.google_compute_instance.pg", "ssh://35.1.2.3",
+				[]string{"google_compute_instance", "db", "v3"}, ""},
+		},
+	}
+
+	for name := range terraformTests {
+		t.Run(name, func(t *testing.T) {
+			hosts := NewTerraformSource(filepath.Join(dir, name, "terraform.tfstate"), "terraform", 1).Hosts()
+			checkHosts(t, hosts, expected[name])
+			if x := []string{"54.1.2.3", "10.0.0.5"}; len(hosts) > 0 && !reflect.DeepEqual(hosts[0].Meta().IPs, x) {
+				t.Errorf("Bad IPs. Expected=%v, Got=%v", x, hosts[0].Meta().IPs)
+			}
+		})
+	}
+}
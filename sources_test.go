@@ -8,7 +8,16 @@
 
 package ssh
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 type tHost struct {
 	Hostname string
@@ -82,6 +91,7 @@ var hostnameTests = []struct {
 	// Plain old hostnames and IPs
 	{"localhost", true},
 	{"::1", true},
+	{"fe80::1%en0", true},
 	{"127.0.0.1", true},
 	{"google.com", true},
 	{"host.google.com", true},
@@ -90,10 +100,25 @@ var hostnameTests = []struct {
 	{"host.google.com:22", false},
 	{"127.0.0.1:22", false},
 	{"[::1]:22", false},
+	// Underscores are invalid per RFC 1123, but common in practice
+	{"host_google_com", true},
+	{"host_1", true},
+	// Trailing dot
+	{"host.google.com.", true},
+	// Internationalised names
+	{"bücher.example", true},
+	{"xn--bcher-kva.example", true},
 	// Bad hostnames
-	{"host_google_com", false},
 	{"host google com", false},
 	{"host google com:22", false},
+	{"-.-..", false},
+	{"host..google.com", false},
+	{"-host.google.com", false},
+	{"host-.google.com", false},
+	{".", false},
+	{"bücher example", false},
+	{strings.Repeat("a", 64) + ".com", false},
+	{strings.Repeat("a.", 127) + "com", false},
 }
 
 // TestValidHostname tests validHostname
@@ -105,3 +130,194 @@ func TestValidHostname(t *testing.T) {
 		}
 	}
 }
+
+// TestKnownHostsMeta tests that IP addresses on a known_hosts line are
+// added to all its hosts.
+func TestKnownHostsMeta(t *testing.T) {
+	hosts := parseKnownHostsLine("machine.example.com,10.0.0.1 ecdsa-sha2-nistp256 AAAA", "")
+	if len(hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(hosts))
+	}
+	for _, h := range hosts {
+		ips := h.Meta().IPs
+		if len(ips) != 1 || ips[0] != "10.0.0.1" {
+			t.Errorf("[%s] Expected IPs=[10.0.0.1], Got=%v", h.Name(), ips)
+		}
+	}
+
+	vars := hosts[0].Meta().Vars()
+	if vars["ips"] != "10.0.0.1" {
+		t.Errorf("Expected ips=10.0.0.1, Got=%q", vars["ips"])
+	}
+}
+
+// testSource is a Source that takes a while to load.
+type testSource struct {
+	name     string
+	priority int
+	delay    time.Duration
+	hosts    []Host
+}
+
+func (s *testSource) Name() string  { return s.name }
+func (s *testSource) Priority() int { return s.priority }
+func (s *testSource) Hosts() []Host {
+	time.Sleep(s.delay)
+	return s.hosts
+}
+
+// testContextSource is a testSource that implements ContextSource.
+type testContextSource struct{ testSource }
+
+func (s *testContextSource) HostsContext(ctx context.Context) ([]Host, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.hosts, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TestLoadSources tests parallel loading of sources.
+func TestLoadSources(t *testing.T) {
+	newSource := func(name string, priority int, delay time.Duration) *testSource {
+		return &testSource{name, priority, delay,
+			[]Host{NewBaseHost(name, name+".example.com", name, "", 22)}}
+	}
+	sources := Sources{
+		newSource("slow", 3, 20*time.Millisecond),
+		&testContextSource{*newSource("hung", 1, time.Hour)},
+		newSource("stuck", 4, time.Hour),
+		newSource("fast", 2, 0),
+	}
+
+	start := time.Now()
+	hosts, timedOut := sources.Load(context.Background(), 100*time.Millisecond, NewDuplicateFilter(DedupNone))
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Loading took too long: %v", d)
+	}
+
+	var names []string
+	for _, h := range hosts {
+		names = append(names, h.Name())
+	}
+	if x := []string{"fast", "slow"}; !reflect.DeepEqual(names, x) {
+		t.Errorf("Expected=%v, Got=%v", x, names)
+	}
+
+	names = nil
+	for _, s := range timedOut {
+		names = append(names, s.Name())
+	}
+	if x := []string{"hung", "stuck"}; !reflect.DeepEqual(names, x) {
+		t.Errorf("Expected timed out=%v, Got=%v", x, names)
+	}
+}
+
+// TestHostCache tests caching of parsed hosts and Include directives.
+func TestHostCache(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"config":      "Include conf.d/*\n\nHost one\n  Port 2222\n",
+		"conf.d/work": "Host two three\n  User bob\n",
+		"other":       "",
+	})
+	names := func(hosts []Host) []string {
+		var l []string
+		for _, h := range hosts {
+			l = append(l, h.Name())
+		}
+		return l
+	}
+	configPath := filepath.Join(dir, "config")
+
+	cache := NewHostCache(filepath.Join(dir, "cache"))
+	load := func() []Host {
+		s := NewConfigSource(configPath, "config", 1)
+		s.Cache = cache
+		return s.Hosts()
+	}
+
+	hosts := load()
+	if x := []string{"two", "three", "one"}; !reflect.DeepEqual(names(hosts), x) {
+		t.Fatalf("Expected=%v, Got=%v", x, names(hosts))
+	}
+
+	cached, ok := cache.Load("config", configPath)
+	if !ok {
+		t.Fatal("Hosts not cached")
+	}
+	if _, ok := cached[0].(*ConfigHost); !ok || cached[0].Username() != "bob" || cached[2].Port() != 2222 {
+		t.Errorf("Bad cached hosts: %#v", cached)
+	}
+	if hosts := load(); hosts[0].Source() != "config" || hosts[0].Meta().Get("file") != configPath {
+		t.Errorf("Source not set on cached host: %#v", hosts[0])
+	}
+
+	// Adding a file to an Included directory invalidates the cache
+	time.Sleep(10 * time.Millisecond)
+	writeTestFiles(t, dir, map[string]string{"conf.d/home": "Host four\n"})
+	if _, ok := cache.Load("config", configPath); ok {
+		t.Error("Cache not invalidated by new file")
+	}
+	if x := []string{"four", "two", "three", "one"}; !reflect.DeepEqual(names(load()), x) {
+		t.Errorf("Expected=%v, Got=%v", x, names(load()))
+	}
+	// Other types of Host aren't cached
+	other := filepath.Join(dir, "other")
+	merged := newMergedHost([]Host{NewBaseHost("a", "a", "test", "", 22), NewBaseHost("b", "a", "test", "", 22)})
+	if err := cache.Store("other", other, []Host{merged}); err == nil {
+		t.Error("Cached a MergedHost")
+	}
+	if _, ok := cache.Load("other", other); ok {
+		t.Error("Loaded uncacheable hosts")
+	}
+}
+
+// TestLoadCachedData tests caching of fetched data and the fallback to
+// stale data.
+func TestLoadCachedData(t *testing.T) {
+	var (
+		calls    int
+		fetchErr error
+		s        = &baseSource{name: "test", Cache: NewHostCache(testDir(t, nil))}
+	)
+	fetch := func() ([]byte, error) {
+		calls++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return []byte(fmt.Sprintf("data%d", calls)), nil
+	}
+	load := func(maxAge time.Duration) string {
+		data, err := s.loadCachedData("test", "key", maxAge, fetch)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		err    error
+		x      string
+		calls  int
+	}{
+		{"fetched", time.Minute, nil, "data1", 1},
+		{"cached", time.Minute, nil, "data1", 1},
+		{"refetched", 0, nil, "data2", 2},
+		{"stale", 0, errors.New("offline"), "data2", 3},
+	}
+	for _, td := range tests {
+		fetchErr = td.err
+		if v := load(td.maxAge); v != td.x || calls != td.calls {
+			t.Errorf("[%s] Expected=%q (%d calls), Got=%q (%d calls)", td.name, td.x, td.calls, v, calls)
+		}
+	}
+
+	// Errors are returned if nothing is cached
+	s.Cache = nil
+	if v := load(time.Minute); v != "error: offline" {
+		t.Errorf("Expected error, Got=%q", v)
+	}
+}
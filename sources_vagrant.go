@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultVagrantTimeout is how long VagrantSource waits for vagrant.
+// vagrant is slow to start, so this is longer than DefaultSourceTimeout.
+const DefaultVagrantTimeout = 10 * time.Second
+
+// How many "vagrant ssh-config" commands VagrantSource runs at once.
+// Vagrant locks its data directory, so running more doesn't help.
+const vagrantWorkers = 4
+
+// vagrantIndex is Vagrant's global machine index.
+type vagrantIndex struct {
+	Version  int                       `json:"version"`
+	Machines map[string]vagrantMachine `json:"machines"`
+}
+
+// vagrantMachine is a machine in the index.
+type vagrantMachine struct {
+	ID             string `json:"-"`
+	Name           string `json:"name"`
+	Provider       string `json:"provider"`
+	State          string `json:"state"`
+	LocalDataPath  string `json:"local_data_path"`
+	VagrantfileDir string `json:"vagrantfile_path"`
+}
+
+// project returns the name of the machine's project, i.e. the name of
+// the directory containing its Vagrantfile.
+func (m vagrantMachine) project() string { return filepath.Base(m.VagrantfileDir) }
+
+// VagrantHost is a running Vagrant machine. Unlike other Hosts, its ssh
+// and mosh commands include its private key (Meta.IdentityFile), as
+// Vagrant machines usually only accept the key Vagrant generated for them.
+// ssh:// URLs can't specify a key, so it isn't used for those.
+type VagrantHost struct {
+	BaseHost
+}
+
+// SSHCmd implements Host.
+func (h *VagrantHost) SSHCmd(path string) *Command { return sshCmd(h, path, h.meta.IdentityFile) }
+
+// MoshCmd implements Host.
+func (h *VagrantHost) MoshCmd(path string) *Command { return moshCmd(h, path, h.meta.IdentityFile) }
+
+// VagrantSource implements Source for the running machines in Vagrant's
+// global machine index (~/.vagrant.d/data/machine-index/index).
+//
+// Hosts are named "<project>/<machine>", and their connection details
+// (hostname, port, user and identity file) are taken from the output of
+// "vagrant ssh-config", which is cached until the index changes.
+// Machines for which vagrant fails are skipped, as their address isn't
+// known.
+type VagrantSource struct {
+	baseSource
+	Command string // Path to vagrant program
+	timeout time.Duration
+}
+
+// NewVagrantSource creates a new VagrantSource for a machine index file.
+func NewVagrantSource(path, name string, priority int) *VagrantSource {
+	s := &VagrantSource{Command: "vagrant", timeout: DefaultVagrantTimeout}
+	s.Filepath = path
+	s.name = name
+	s.priority = priority
+	return s
+}
+
+// Timeout implements TimeoutSource.
+func (s *VagrantSource) Timeout() time.Duration { return s.timeout }
+
+// Hosts implements Source.
+func (s *VagrantSource) Hosts() []Host {
+	if s.hosts == nil {
+		s.HostsContext(context.Background())
+	}
+	return s.hosts
+}
+
+// HostsContext implements ContextSource.
+func (s *VagrantSource) HostsContext(ctx context.Context) ([]Host, error) {
+	if s.hosts != nil {
+		return s.hosts, nil
+	}
+
+	fi, err := os.Stat(s.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	machines, err := readVagrantIndex(s.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		hosts = make([]*VagrantHost, len(machines))
+		jobs  = make(chan int)
+		wg    sync.WaitGroup
+	)
+	for n := 0; n < vagrantWorkers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				m := machines[i]
+				// Index changes whenever a machine is started or stopped
+				key := fmt.Sprintf("%s@%d", m.ID, fi.ModTime().UnixNano())
+				data, fresh := s.Cache.LoadData("vagrant", key, 24*time.Hour)
+				if !fresh {
+					out, err := s.sshConfig(ctx, m.ID)
+					if err != nil {
+						log.Printf("[source/vagrant/%s] ssh-config failed for %s: %v", s.Name(), m.ID, err)
+					} else {
+						data = out
+						if err := s.Cache.StoreData("vagrant", key, data); err != nil {
+							log.Printf("[source/vagrant/%s] error caching ssh-config: %v", s.Name(), err)
+						}
+					}
+				}
+				if data != nil {
+					hosts[i] = vagrantHost(m, data)
+				}
+			}
+		}()
+	}
+	for i := range machines {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	l := []Host{}
+	for _, h := range hosts {
+		if h == nil { // ssh-config failed
+			continue
+		}
+		h.source = s.Name()
+		h.meta.Set("file", s.Filepath)
+		l = append(l, h)
+	}
+	// Don't keep a partial list, so the source is reloaded next time
+	if err := ctx.Err(); err != nil {
+		log.Printf("[source/load/vagrant] %d host(s) from '%s' before %v", len(l), s.Name(), err)
+		return l, err
+	}
+	s.hosts = l
+	log.Printf("[source/load/vagrant] %d host(s) from '%s'", len(s.hosts), s.Name())
+	return s.hosts, nil
+}
+
+// sshConfig returns the output of "vagrant ssh-config <id>".
+func (s *VagrantSource) sshConfig(ctx context.Context, id string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.Command, "ssh-config", id)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// readVagrantIndex returns the running machines in the index, sorted by
+// project and name.
+func readVagrantIndex(path string) ([]vagrantMachine, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var idx vagrantIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+
+	var machines []vagrantMachine
+	for id, m := range idx.Machines {
+		if m.State != "running" {
+			continue
+		}
+		m.ID = id
+		machines = append(machines, m)
+	}
+	sort.Slice(machines, func(i, j int) bool {
+		a, b := machines[i], machines[j]
+		if a.project() != b.project() {
+			return a.project() < b.project()
+		}
+		return a.Name < b.Name
+	})
+	return machines, nil
+}
+
+// vagrantHost creates a Host for machine m from the output of
+// "vagrant ssh-config". Vagrant's defaults are used for any missing
+// values.
+func vagrantHost(m vagrantMachine, sshConfig []byte) *VagrantHost {
+	h := &VagrantHost{BaseHost{
+		name:     m.project() + "/" + m.Name,
+		hostname: "127.0.0.1",
+		port:     2222,
+		username: "vagrant",
+	}}
+	// Key Vagrant generates for each machine
+	key := filepath.Join(m.LocalDataPath, "machines", m.Name, m.Provider, "private_key")
+	if _, err := os.Stat(key); err == nil {
+		h.meta.IdentityFile = key
+	}
+
+	var identity string
+	scanner := bufio.NewScanner(bytes.NewReader(sshConfig))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value := strings.Trim(strings.Join(fields[1:], " "), `"`)
+		switch strings.ToLower(fields[0]) {
+		case "hostname":
+			h.hostname = value
+		case "port":
+			if port, err := strconv.Atoi(value); err == nil {
+				h.port = port
+			}
+		case "user":
+			h.username = value
+		case "identityfile":
+			// First key is the one Vagrant uses
+			if identity == "" {
+				identity = value
+			}
+		}
+	}
+	if identity != "" {
+		h.meta.IdentityFile = identity
+	}
+
+	h.meta.AddTag("vagrant", m.project(), m.Provider)
+	h.meta.Description = m.VagrantfileDir
+	// Don't add loopback address, as all forwarded machines share it
+	if ip := net.ParseIP(h.hostname); ip != nil && !ip.IsLoopback() {
+		h.meta.AddIP(h.hostname)
+	}
+	return h
+}
@@ -0,0 +1,113 @@
+//
+// Copyright (c) 2026 Dean Jackson <deanishe@deanishe.net>
+//
+// MIT Licence. See http://opensource.org/licenses/MIT
+//
+// Created on 2026-10-18
+//
+
+package ssh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var vagrantIndexData = `{
+  "version": 1,
+  "machines": {
+    "aaa111": {
+      "local_data_path": "/projects/webapp/.vagrant", "name": "default",
+      "provider": "virtualbox", "state": "running", "vagrantfile_path": "/projects/webapp"
+    },
+    "bbb222": {
+      "local_data_path": "/projects/cluster/.vagrant", "name": "node2",
+      "provider": "libvirt", "state": "running", "vagrantfile_path": "/projects/cluster"
+    },
+    "ccc333": {
+      "local_data_path": "/projects/cluster/.vagrant", "name": "node1",
+      "provider": "libvirt", "state": "poweroff", "vagrantfile_path": "/projects/cluster"
+    }
+  }
+}`
+
+// Fake vagrant that only knows machine aaa111.
+var vagrantScript = `#!/bin/sh
+if [ "$2" = "aaa111" ]; then
+  cat <<EOF
+Host default
+  HostName 127.0.0.1
+  User vagrant
+  Port 2200
+  UserKnownHostsFile /dev/null
+  IdentityFile "/projects/webapp/.vagrant/machines/default/virtualbox/private_key"
+  IdentityFile /home/user/.vagrant.d/insecure_private_key
+EOF
+  exit 0
+fi
+echo "machine not found" >&2
+exit 1
+`
+
+// TestVagrantSource tests reading running machines from the index.
+func TestVagrantSource(t *testing.T) {
+	dir := testDir(t, map[string]string{"index": vagrantIndexData, "vagrant": vagrantScript})
+	index := filepath.Join(dir, "index")
+	script := filepath.Join(dir, "vagrant")
+
+	s := NewVagrantSource(index, "vagrant", 1)
+	s.Command = script
+	s.Cache = NewHostCache(filepath.Join(dir, "cache"))
+	// cluster/node2 is skipped, as vagrant failed, and cluster/node1
+	// isn't running
+	hosts := s.Hosts()
+	checkHosts(t, hosts, []testHost{
+		{"webapp/default", "ssh://vagrant@127.0.0.1:2200", []string{"vagrant", "webapp", "virtualbox"}, "/projects/webapp"},
+	})
+	// The first key is used
+	if x := "/projects/webapp/.vagrant/machines/default/virtualbox/private_key"; len(hosts) > 0 && hosts[0].Meta().IdentityFile != x {
+		t.Errorf("Bad key. Expected=%q, Got=%q", x, hosts[0].Meta().IdentityFile)
+	}
+
+	// Output of ssh-config is cached
+	if err := os.Remove(script); err != nil {
+		t.Fatal(err)
+	}
+	s = NewVagrantSource(index, "vagrant", 1)
+	s.Command = script
+	s.Cache = NewHostCache(filepath.Join(dir, "cache"))
+	if h := s.Hosts()[0]; h.Port() != 2200 {
+		t.Errorf("Bad cached port. Expected=2200, Got=%d", h.Port())
+	}
+	// Timing out is an error, not an empty source
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = NewVagrantSource(index, "vagrant", 1)
+	s.Command = script
+	s.Cache = NewHostCache(filepath.Join(dir, "cache2"))
+	if _, err := s.HostsContext(ctx); err != context.Canceled {
+		t.Errorf("Bad error. Expected=%v, Got=%v", context.Canceled, err)
+	}
+}
+
+// TestVagrantHostCommands tests that the machine's key is passed to ssh.
+func TestVagrantHostCommands(t *testing.T) {
+	h := &VagrantHost{BaseHost{name: "host", hostname: "127.0.0.1", username: "vagrant", port: 2222}}
+	h.meta.IdentityFile = "/vm/.vagrant/private key"
+	if s := h.SSHCmd("").String(); s != "ssh -p 2222 -i '/vm/.vagrant/private key' vagrant@127.0.0.1" {
+		t.Errorf("Bad ssh command: %s", s)
+	}
+	x := `mosh --ssh 'ssh -p 2222 -i '"'"'/vm/.vagrant/private key'"'"'' vagrant@127.0.0.1`
+	if s := h.MoshCmd("").String(); s != x {
+		t.Errorf("Bad mosh command. Expected=%s, Got=%s", x, s)
+	}
+
+	// Other hosts' keys aren't passed to ssh
+	b := NewBaseHost("host", "127.0.0.1", "", "vagrant", 2222)
+	b.Meta().IdentityFile = "/vm/.vagrant/private key"
+	if s := b.SSHCmd("").String(); s != "ssh -p 2222 vagrant@127.0.0.1" {
+		t.Errorf("Bad ssh command: %s", s)
+	}
+}